@@ -0,0 +1,110 @@
+package tmsplit
+
+// Clone returns a copy of the properties
+func (props Properties) Clone() Properties {
+	if props == nil {
+		return nil
+	}
+	return append(Properties{}, props...)
+}
+
+// Clone returns a deep copy of the object
+func (o Object) Clone() Object {
+	if o.Polygon != nil {
+		o.Polygon = append([]Point{}, o.Polygon...)
+	}
+	if o.Polyline != nil {
+		o.Polyline = append([]Point{}, o.Polyline...)
+	}
+	o.Properties = o.Properties.Clone()
+	return o
+}
+
+func cloneObjects(objects []Object) []Object {
+	if objects == nil {
+		return nil
+	}
+
+	ret := make([]Object, len(objects))
+	for i, o := range objects {
+		ret[i] = o.Clone()
+	}
+	return ret
+}
+
+// Clone returns a deep copy of the layer, including its data, objects and child layers
+func (l Layer) Clone() Layer {
+	if l.Chunks != nil {
+		l.Chunks = append([]Chunk{}, l.Chunks...)
+	}
+	if l.XMLData.Chunks != nil {
+		l.XMLData.Chunks = append([]XMLChunk{}, l.XMLData.Chunks...)
+	}
+	l.Objects = cloneObjects(l.Objects)
+	l.Layers = cloneLayers(l.Layers)
+	l.Properties = l.Properties.Clone()
+	return l
+}
+
+func cloneLayers(layers []Layer) []Layer {
+	if layers == nil {
+		return nil
+	}
+
+	ret := make([]Layer, len(layers))
+	for i, l := range layers {
+		ret[i] = l.Clone()
+	}
+	return ret
+}
+
+// Clone returns a deep copy of the tile
+func (t Tile) Clone() Tile {
+	if t.Animation != nil {
+		t.Animation = append([]Frame{}, t.Animation...)
+	}
+	if t.Terrain != nil {
+		t.Terrain = append(TileTerrain{}, t.Terrain...)
+	}
+	t.ObjectGroup = t.ObjectGroup.Clone()
+	t.Properties = t.Properties.Clone()
+	return t
+}
+
+// Clone returns a deep copy of the tileset
+func (ts Tileset) Clone() Tileset {
+	ts.Properties = ts.Properties.Clone()
+	if ts.Terrains != nil {
+		terrains := make([]Terrain, len(ts.Terrains))
+		for i, t := range ts.Terrains {
+			terrains[i] = t
+			terrains[i].Properties = t.Properties.Clone()
+		}
+		ts.Terrains = terrains
+	}
+	if ts.Tiles != nil {
+		tiles := make([]Tile, len(ts.Tiles))
+		for i, t := range ts.Tiles {
+			tiles[i] = t.Clone()
+		}
+		ts.Tiles = tiles
+	}
+	if ts.WangSets != nil {
+		ts.WangSets = append([]WangSet{}, ts.WangSets...)
+	}
+	return ts
+}
+
+// Clone returns a deep copy of the tilemap
+func (tm Tilemap) Clone() Tilemap {
+	tm.Layers = cloneLayers(tm.Layers)
+	tm.Properties = tm.Properties.Clone()
+	if tm.Tilesets != nil {
+		tilesets := make([]Tileset, len(tm.Tilesets))
+		for i, ts := range tm.Tilesets {
+			tilesets[i] = ts.Clone()
+		}
+		tm.Tilesets = tilesets
+	}
+	return tm
+}
@@ -1,12 +1,15 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
-	"math"
+	"io"
 	"os"
+	"os/signal"
 	"path"
+	"path/filepath"
 	"strings"
 
 	"github.com/codename-pyoko/tmsplit"
@@ -14,33 +17,42 @@ import (
 )
 
 var outputFmt string
+var outputFormat string
 var masterFile string
 var pretty bool
 var logLevel string
 
-func saveTilemaps(tilemaps []tmsplit.Tilemap) error {
-	for index, tm := range tilemaps {
-		f, err := os.Create(fmt.Sprintf(outputFmt, index))
-		if err != nil {
-			logrus.Fatalf("failed to create output file: %w", err)
-		}
-		defer f.Close()
+func saveTilemap(index int, tm tmsplit.Tilemap) error {
+	return writeTilemap(fmt.Sprintf(outputFmt, index), tm)
+}
+
+func writeTilemap(name string, tm tmsplit.Tilemap) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer f.Close()
 
+	if outputFormat == "tmx" {
+		if err := tmsplit.EncodeXML(f, tm); err != nil {
+			return fmt.Errorf("failed to encode tilemap: %w", err)
+		}
+	} else {
 		encoder := json.NewEncoder(f)
 		if pretty {
 			encoder.SetIndent("", "\t")
 		}
 
 		if err := encoder.Encode(&tm); err != nil {
-			logrus.Fatalf("failed to encode tilemap: %v", err)
+			return fmt.Errorf("failed to encode tilemap: %w", err)
 		}
-		logrus.Debugf("saved tilemap to %s", f.Name())
 	}
+	logrus.Debugf("saved tilemap to %s", f.Name())
 
 	return nil
 }
 
-func saveMasterFile(master tmsplit.MasterFile) error {
+func saveMasterFile(ctx context.Context, master tmsplit.MasterFile) error {
 	f, err := os.Create(masterFile)
 	if err != nil {
 		return fmt.Errorf("failed to open master file: %w", err)
@@ -48,7 +60,17 @@ func saveMasterFile(master tmsplit.MasterFile) error {
 
 	defer f.Close()
 
-	if err := tmsplit.FormatTypescript(f, master); err != nil {
+	// json master files can be read back by the merge command
+	if path.Ext(masterFile) == ".json" {
+		encoder := json.NewEncoder(f)
+		if pretty {
+			encoder.SetIndent("", "\t")
+		}
+
+		if err := encoder.Encode(&master); err != nil {
+			return fmt.Errorf("failed to encode master file: %w", err)
+		}
+	} else if err := tmsplit.FormatTypescriptContext(ctx, f, master); err != nil {
 		return fmt.Errorf("failed to format master file: %w", err)
 	}
 
@@ -57,16 +79,37 @@ func saveMasterFile(master tmsplit.MasterFile) error {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "merge" {
+		merge(os.Args[2:])
+		return
+	}
+
 	tiledJSON := flag.String("json", "", "Tiled JSON tilemap")
 	tiledXML := flag.String("tmx", "", "Tiled TMX (xml) tilemap")
 
 	flag.StringVar(&outputFmt, "out", "", "Output fmt string. %d for index")
+	flag.StringVar(&outputFormat, "format", "json", "Output format of chunks (json or tmx)")
 	flag.BoolVar(&pretty, "pretty", false, "If output should be pretty printed")
-	flag.StringVar(&masterFile, "master", "", "Master output file")
+	flag.StringVar(&masterFile, "master", "", "Master output file, written as json if it ends in .json and as typescript otherwise")
 
-	var chunkHeight, chunkWidth int
-	flag.IntVar(&chunkWidth, "chunkwidth", 100, "Width of each chunk")
-	flag.IntVar(&chunkHeight, "chunkheight", 100, "Height of each chunk")
+	opts := tmsplit.DefaultSplitOptions()
+	flag.IntVar(&opts.ChunkWidth, "chunkwidth", opts.ChunkWidth, "Width of each chunk")
+	flag.IntVar(&opts.ChunkHeight, "chunkheight", opts.ChunkHeight, "Height of each chunk")
+	flag.IntVar(&opts.Padding, "padding", opts.Padding, "Number of tiles from neighbouring chunks to include on each side of a chunk")
+	flag.StringVar((*string)(&opts.EmptyChunks), "emptychunks", string(opts.EmptyChunks), "What to do with chunks without tiles or objects. Empty keeps them, 'skip' leaves them out and 'mark' flags them in the master file without saving them")
+	flag.StringVar((*string)(&opts.Objects), "objects", string(opts.Objects), "Which chunks get an object. Empty uses the chunk of its position, 'centroid' the chunk of its centroid, 'duplicate' every chunk it overlaps and 'clip' clips it to every chunk it overlaps")
+	flag.StringVar((*string)(&opts.ObjectIDs), "objectids", string(opts.ObjectIDs), "Ids of the objects in chunks. Empty keeps the source ids, 'renumber' numbers the objects of each chunk from 1 and keeps the source id in a property")
+	flag.BoolVar(&opts.Deduplicate, "dedup", opts.Deduplicate, "Save chunks with identical content once and share the url in the master file")
+	flag.BoolVar(&opts.PruneTilesets, "prunetilesets", opts.PruneTilesets, "Leave out the tilesets a chunk doesn't use and renumber its gids")
+	flag.IntVar(&opts.Workers, "workers", opts.Workers, "Number of chunks to create concurrently. Uses the number of CPUs if 0")
+	flag.BoolVar(&opts.ExcludeHidden, "excludehidden", opts.ExcludeHidden, "Leave hidden layers out of the chunks")
+	includeLayers := flag.String("includelayers", "", "Comma separated names of the layers to keep in the chunks. Keeps all if empty")
+	excludeLayers := flag.String("excludelayers", "", "Comma separated names of the layers to leave out of the chunks")
+
+	compression := flag.String("compression", "", "Compression of chunk layer data (none, zlib, gzip or zstd). Keeps source compression if empty")
+	resolveTilesets := flag.Bool("resolvetilesets", false, "Load external tilesets relative to the source file, keeping the references in chunks")
+	embedTilesets := flag.Bool("embedtilesets", false, "Load external tilesets relative to the source file and embed them in chunks")
+	encoding := flag.String("encoding", "", "Encoding of chunk layer data (csv or base64). Keeps source encoding if empty")
 
 	flag.Parse()
 
@@ -82,14 +125,24 @@ func main() {
 		return
 	}
 
+	if outputFormat != "json" && outputFormat != "tmx" {
+		logrus.Errorf("Unsupported output format '%s'", outputFormat)
+		flag.Usage()
+		return
+	}
+
+	// abandon work on interrupt
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
 	var sourceFile string
-	var parser tmsplit.TilemapDecoder
+	var parser func(context.Context, io.Reader) (tmsplit.Tilemap, error)
 	if *tiledJSON != "" {
 		sourceFile = *tiledJSON
-		parser = tmsplit.ParseJSON
+		parser = tmsplit.ParseJSONContext
 	} else if *tiledXML != "" {
 		sourceFile = *tiledXML
-		parser = tmsplit.ParseXML
+		parser = tmsplit.ParseXMLContext
 	}
 
 	sourceNoExt := strings.TrimSuffix(sourceFile, path.Ext(sourceFile))
@@ -104,33 +157,108 @@ func main() {
 	}
 
 	if outputFmt == "" {
-		outputFmt = fmt.Sprintf("%s-%%d.json", sourceNoExt)
+		outputFmt = fmt.Sprintf("%s-%%d.%s", sourceNoExt, outputFormat)
 	}
 
-	tilemap, err := parser(f)
+	tilemap, err := parser(ctx, f)
 	if err != nil {
 		logrus.Fatalf("failed to parse tilemap: %v", err)
 	}
 
-	tilemaps, err := tmsplit.Split(tilemap, chunkWidth, chunkHeight)
-	if err != nil {
-		logrus.Fatalf("failed to split map: %v", err)
+	if *resolveTilesets || *embedTilesets {
+		absSource, err := filepath.Abs(sourceFile)
+		if err != nil {
+			logrus.Fatalf("failed to find source file directory: %v", err)
+		}
+
+		// the file system is rooted at the volume of the source file, like C:\ on windows
+		root := filepath.VolumeName(absSource) + string(filepath.Separator)
+		dir, err := filepath.Rel(root, filepath.Dir(absSource))
+		if err != nil {
+			logrus.Fatalf("failed to find source file directory: %v", err)
+		}
+
+		tilemap, err = tmsplit.ResolveTilesets(tilemap, os.DirFS(root), filepath.ToSlash(dir), *embedTilesets)
+		if err != nil {
+			logrus.Fatalf("failed to resolve tilesets: %v", err)
+		}
+	}
+
+	if *includeLayers != "" {
+		opts.IncludeLayers = strings.Split(*includeLayers, ",")
+	}
+	if *excludeLayers != "" {
+		opts.ExcludeLayers = strings.Split(*excludeLayers, ",")
 	}
 
-	widthInTilemaps := int(math.Ceil(math.Max(float64(tilemap.WidthInTiles)/float64(chunkHeight), 1.0)))
-	master, err := tmsplit.CreateMasterFile(tilemaps, path.Base(sourceFile), widthInTilemaps)
+	switch *compression {
+	case "":
+	case "none":
+		c := tmsplit.NoCompression
+		opts.Compression = &c
+	case string(tmsplit.Zlib), string(tmsplit.Gzip), string(tmsplit.Zstd):
+		c := tmsplit.Compression(*compression)
+		opts.Compression = &c
+	default:
+		logrus.Fatalf("unsupported compression '%s'", *compression)
+	}
+
+	switch *encoding {
+	case "":
+	case string(tmsplit.EncodingCSV), string(tmsplit.EncodingBase64):
+		e := tmsplit.Encoding(*encoding)
+		opts.Encoding = &e
+	default:
+		logrus.Fatalf("unsupported encoding '%s'", *encoding)
+	}
+
+	if err := opts.Validate(); err != nil {
+		logrus.Fatalf("invalid options: %v", err)
+	}
+
+	// chunk urls in the master file are relative to it
+	absMaster, err := filepath.Abs(masterFile)
+	if err != nil {
+		logrus.Fatalf("failed to find master file directory: %v", err)
+	}
+	absOutput, err := filepath.Abs(outputFmt)
+	if err != nil {
+		logrus.Fatalf("failed to find chunk file directory: %v", err)
+	}
+	urlFmt, err := filepath.Rel(filepath.Dir(absMaster), absOutput)
 	if err != nil {
-		logrus.Fatalf("failed to create master file: %v", err)
+		logrus.Fatalf("failed to find chunk files from master file: %v", err)
 	}
+	urlFmt = filepath.ToSlash(urlFmt)
 
-	if err := saveMasterFile(master); err != nil {
-		logrus.Fatalf("failed to save master file: %v", err)
+	// chunks are saved as soon as they're created to keep memory usage down
+	var master tmsplit.MasterFile
+	nchunks := 0
+	err = tmsplit.SplitFuncContext(ctx, tilemap, opts, func(info tmsplit.ChunkInfo, tm tmsplit.Tilemap) error {
+		if info.Empty || info.Duplicate {
+			master.AddTilemap(info.Index, tm, path.Base(sourceFile), urlFmt)
+			return nil
+		}
+
+		if err := saveTilemap(info.Index, tm); err != nil {
+			return fmt.Errorf("failed to save tilemap %d: %w", info.Index, err)
+		}
+
+		master.AddTilemap(info.Index, tm, path.Base(sourceFile), urlFmt)
+		nchunks++
+		return nil
+	})
+	if err != nil {
+		logrus.Fatalf("failed to split map: %v", err)
 	}
-	logrus.Infof("master file saved to '%s'", masterFile)
 
-	if err := saveTilemaps(tilemaps); err != nil {
-		logrus.Fatalf("failed to save tilemaps: %v", err)
+	logrus.Infof("tilemap split to %d chunks and saved to pattern '%s'", nchunks, outputFmt)
+	if opts.Deduplicate {
+		logrus.Infof("%d duplicate chunks share the file of an identical chunk", master.Deduplicated)
 	}
 
-	logrus.Infof("tilemap split to %d chunks and saved to pattern '%s'", len(tilemaps), outputFmt)
+	if err := saveMasterFile(ctx, master); err != nil {
+		logrus.Fatalf("failed to save master file: %v", err)
+	}
+	logrus.Infof("master file saved to '%s'", masterFile)
 }
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+
+	"github.com/codename-pyoko/tmsplit"
+	"github.com/sirupsen/logrus"
+)
+
+func loadMasterFile(name string) (tmsplit.MasterFile, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return tmsplit.MasterFile{}, fmt.Errorf("failed to open master file: %w", err)
+	}
+	defer f.Close()
+
+	var master tmsplit.MasterFile
+	if err := json.NewDecoder(f).Decode(&master); err != nil {
+		return tmsplit.MasterFile{}, fmt.Errorf("failed to decode master file: %w", err)
+	}
+
+	return master, nil
+}
+
+func loadChunk(ctx context.Context, name string) (tmsplit.Tilemap, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return tmsplit.Tilemap{}, fmt.Errorf("failed to open chunk: %w", err)
+	}
+	defer f.Close()
+
+	var parser func(context.Context, io.Reader) (tmsplit.Tilemap, error) = tmsplit.ParseJSONContext
+	if path.Ext(name) == ".tmx" {
+		parser = tmsplit.ParseXMLContext
+	}
+
+	return parser(ctx, f)
+}
+
+// merge is the merge command, rebuilding a map from the chunks listed in a json master file
+func merge(args []string) {
+	flags := flag.NewFlagSet("merge", flag.ExitOnError)
+	master := flags.String("master", "", "Json master file of the chunks. Chunk urls are relative to it")
+	out := flags.String("out", "", "Output file of the merged tilemap")
+	format := flags.String("format", "json", "Output format of the merged tilemap (json or tmx)")
+	flags.BoolVar(&pretty, "pretty", false, "If output should be pretty printed")
+
+	flags.Parse(args)
+
+	if *master == "" || *out == "" {
+		logrus.Errorf("Must specify master file and output file")
+		flags.Usage()
+		return
+	}
+
+	if *format != "json" && *format != "tmx" {
+		logrus.Errorf("Unsupported output format '%s'", *format)
+		flags.Usage()
+		return
+	}
+
+	// abandon work on interrupt
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	masterFile, err := loadMasterFile(*master)
+	if err != nil {
+		logrus.Fatalf("failed to load master file: %v", err)
+	}
+
+	// deduplicated chunks share a url
+	loaded := map[string]tmsplit.Tilemap{}
+	chunks := make([]tmsplit.Tilemap, len(masterFile.Tilemaps))
+	for i, entry := range masterFile.Tilemaps {
+		if entry.Empty {
+			continue
+		}
+
+		if tm, ok := loaded[entry.URL]; ok {
+			chunks[i] = tm
+			continue
+		}
+
+		tm, err := loadChunk(ctx, filepath.Join(filepath.Dir(*master), filepath.FromSlash(entry.URL)))
+		if err != nil {
+			logrus.Fatalf("failed to load chunk '%s': %v", entry.Key, err)
+		}
+		loaded[entry.URL] = tm
+		chunks[i] = tm
+	}
+
+	tilemap, err := tmsplit.Merge(masterFile, chunks)
+	if err != nil {
+		logrus.Fatalf("failed to merge chunks: %v", err)
+	}
+
+	outputFormat = *format
+	if err := writeTilemap(*out, tilemap); err != nil {
+		logrus.Fatalf("failed to save tilemap: %v", err)
+	}
+
+	logrus.Infof("%d chunks merged and saved to '%s'", len(loaded), *out)
+}
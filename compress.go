@@ -0,0 +1,83 @@
+package tmsplit
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func decompress(b []byte, compression Compression) ([]byte, error) {
+	switch compression {
+	case NoCompression:
+		return b, nil
+
+	case Zlib:
+		r, err := zlib.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create zlib reader: %w", err)
+		}
+		defer r.Close()
+		return ioutil.ReadAll(r)
+
+	case Gzip:
+		r, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create gzip reader: %w", err)
+		}
+		defer r.Close()
+		return ioutil.ReadAll(r)
+
+	case Zstd:
+		r, err := zstd.NewReader(nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create zstd reader: %w", err)
+		}
+		defer r.Close()
+		return r.DecodeAll(b, nil)
+	}
+
+	return nil, fmt.Errorf("unsupported compression '%s'", compression)
+}
+
+func compress(b []byte, compression Compression) ([]byte, error) {
+	switch compression {
+	case NoCompression:
+		return b, nil
+
+	case Zlib:
+		buf := bytes.Buffer{}
+		w := zlib.NewWriter(&buf)
+		if _, err := w.Write(b); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+
+	case Gzip:
+		buf := bytes.Buffer{}
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(b); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+
+	case Zstd:
+		w, err := zstd.NewWriter(nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create zstd writer: %w", err)
+		}
+		defer w.Close()
+		return w.EncodeAll(b, nil), nil
+	}
+
+	return nil, fmt.Errorf("unsupported compression '%s'", compression)
+}
@@ -0,0 +1,104 @@
+package tmsplit
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestCompressRoundTrip(t *testing.T) {
+	data := bytes.Repeat([]byte{1, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0x80}, 100)
+
+	for _, compression := range []Compression{NoCompression, Zlib, Gzip, Zstd} {
+		t.Run(string(compression), func(t *testing.T) {
+			compressed, err := compress(data, compression)
+			if err != nil {
+				t.Fatalf("failed to compress: %v", err)
+			}
+
+			decompressed, err := decompress(compressed, compression)
+			if err != nil {
+				t.Fatalf("failed to decompress: %v", err)
+			}
+
+			if !bytes.Equal(decompressed, data) {
+				t.Errorf("got %v, want %v", decompressed, data)
+			}
+		})
+	}
+}
+
+func TestUnsupportedCompression(t *testing.T) {
+	if _, err := compress(nil, "lz4"); err == nil {
+		t.Error("compress: expected an error")
+	}
+	if _, err := decompress(nil, "lz4"); err == nil {
+		t.Error("decompress: expected an error")
+	}
+}
+
+func TestEncodeLayerDataRoundTrip(t *testing.T) {
+	data := []GID{1, 2, 3, NewGID(4, true, false, false), 0, 6}
+
+	tests := []struct {
+		encoding    Encoding
+		compression Compression
+	}{
+		{EncodingCSV, NoCompression},
+		{EncodingBase64, NoCompression},
+		{EncodingBase64, Zlib},
+		{EncodingBase64, Gzip},
+		{EncodingBase64, Zstd},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.encoding)+"/"+string(tt.compression), func(t *testing.T) {
+			encoded, err := encodeLayerData(data, tt.encoding, tt.compression)
+			if err != nil {
+				t.Fatalf("failed to encode: %v", err)
+			}
+
+			decoded, err := decodeLayerData(Layer{Data: encoded, Encoding: tt.encoding, Compression: tt.compression})
+			if err != nil {
+				t.Fatalf("failed to decode: %v", err)
+			}
+
+			if !reflect.DeepEqual(decoded, data) {
+				t.Errorf("got %v, want %v", decoded, data)
+			}
+		})
+	}
+}
+
+// base64.tmx has the same tiles in a layer for each compression, compressed by other tools than this package
+func TestDecodeBase64Fixture(t *testing.T) {
+	f, err := os.Open("testdata/base64.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	tm, err := ParseXML(f)
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	want := []GID{1, 2, 3, NewGID(4, true, false, false), 0, 6}
+	if len(tm.Layers) != 4 {
+		t.Fatalf("got %d layers, want 4", len(tm.Layers))
+	}
+
+	for _, layer := range tm.Layers {
+		t.Run(layer.Name, func(t *testing.T) {
+			got, err := decodeLayerData(layer)
+			if err != nil {
+				t.Fatalf("failed to decode: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
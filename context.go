@@ -0,0 +1,58 @@
+package tmsplit
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// CanceledError is returned when the context of an operation is done before the operation completes
+type CanceledError struct {
+	// Op is the operation that was canceled
+	Op string
+	// Err is the error of the context, context.Canceled or context.DeadlineExceeded
+	Err error
+}
+
+func (e *CanceledError) Error() string {
+	return fmt.Sprintf("%s canceled: %v", e.Op, e.Err)
+}
+
+func (e *CanceledError) Unwrap() error {
+	return e.Err
+}
+
+func checkContext(ctx context.Context, op string) error {
+	if err := ctx.Err(); err != nil {
+		return &CanceledError{Op: op, Err: err}
+	}
+	return nil
+}
+
+// contextReader stops reading once the context is done
+type contextReader struct {
+	ctx context.Context
+	op  string
+	r   io.Reader
+}
+
+func (cr contextReader) Read(p []byte) (int, error) {
+	if err := checkContext(cr.ctx, cr.op); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
+
+// contextWriter stops writing once the context is done
+type contextWriter struct {
+	ctx context.Context
+	op  string
+	w   io.Writer
+}
+
+func (cw contextWriter) Write(p []byte) (int, error) {
+	if err := checkContext(cw.ctx, cw.op); err != nil {
+		return 0, err
+	}
+	return cw.w.Write(p)
+}
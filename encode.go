@@ -0,0 +1,462 @@
+package tmsplit
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type xmlProperty struct {
+	Name  string       `xml:"name,attr"`
+	Type  PropertyType `xml:"type,attr,omitempty"`
+	Value string       `xml:"value,attr"`
+}
+
+type xmlProperties struct {
+	Properties []xmlProperty `xml:"property"`
+}
+
+type xmlImageOut struct {
+	Source string `xml:"source,attr"`
+	Width  int    `xml:"width,attr,omitempty"`
+	Height int    `xml:"height,attr,omitempty"`
+}
+
+type xmlPoints struct {
+	Points string `xml:"points,attr"`
+}
+
+type xmlObject struct {
+	XMLName    xml.Name       `xml:"object"`
+	ID         int            `xml:"id,attr"`
+	Name       string         `xml:"name,attr,omitempty"`
+	Type       string         `xml:"type,attr,omitempty"`
+	GID        GID            `xml:"gid,attr,omitempty"`
+	X          float64        `xml:"x,attr"`
+	Y          float64        `xml:"y,attr"`
+	Width      float64        `xml:"width,attr,omitempty"`
+	Height     float64        `xml:"height,attr,omitempty"`
+	Rotation   float64        `xml:"rotation,attr,omitempty"`
+	Visible    *int           `xml:"visible,attr"`
+	Template   string         `xml:"template,attr,omitempty"`
+	Properties *xmlProperties `xml:"properties"`
+	Ellipse    *struct{}      `xml:"ellipse"`
+	Point      *struct{}      `xml:"point"`
+	Polygon    *xmlPoints     `xml:"polygon"`
+	Polyline   *xmlPoints     `xml:"polyline"`
+}
+
+type xmlDataChunk struct {
+	X      int    `xml:"x,attr"`
+	Y      int    `xml:"y,attr"`
+	Width  int    `xml:"width,attr"`
+	Height int    `xml:"height,attr"`
+	Data   string `xml:",innerxml"`
+}
+
+type xmlLayerData struct {
+	Encoding    Encoding       `xml:"encoding,attr,omitempty"`
+	Compression Compression    `xml:"compression,attr,omitempty"`
+	Data        string         `xml:",innerxml"`
+	Chunks      []xmlDataChunk `xml:"chunk"`
+}
+
+type xmlLayerOut struct {
+	XMLName    xml.Name
+	ID         int            `xml:"id,attr,omitempty"`
+	Name       string         `xml:"name,attr"`
+	Width      int            `xml:"width,attr,omitempty"`
+	Height     int            `xml:"height,attr,omitempty"`
+	DrawOrder  DrawOrder      `xml:"draworder,attr,omitempty"`
+	Trans      string         `xml:"trans,attr,omitempty"`
+	Visible    *int           `xml:"visible,attr"`
+	Opacity    *float64       `xml:"opacity,attr"`
+	OffsetX    float64        `xml:"offsetx,attr,omitempty"`
+	OffsetY    float64        `xml:"offsety,attr,omitempty"`
+	Properties *xmlProperties `xml:"properties"`
+	Image      *xmlImageOut   `xml:"image"`
+	Data       *xmlLayerData  `xml:"data"`
+	Objects    []xmlObject    `xml:"object"`
+	Layers     []xmlLayerOut
+}
+
+type xmlFrame struct {
+	TileID   int `xml:"tileid,attr"`
+	Duration int `xml:"duration,attr"`
+}
+
+type xmlTile struct {
+	ID          int            `xml:"id,attr"`
+	Terrain     string         `xml:"terrain,attr,omitempty"`
+	Probability float64        `xml:"probability,attr,omitempty"`
+	Properties  *xmlProperties `xml:"properties"`
+	Image       *xmlImageOut   `xml:"image"`
+	ObjectGroup *xmlLayerOut   `xml:"objectgroup"`
+	Animation   []xmlFrame     `xml:"animation>frame"`
+}
+
+type xmlTerrain struct {
+	Name       string         `xml:"name,attr"`
+	Tile       int            `xml:"tile,attr"`
+	Properties *xmlProperties `xml:"properties"`
+}
+
+type xmlTerrains struct {
+	Terrains []xmlTerrain `xml:"terrain"`
+}
+
+type xmlTileset struct {
+	FirstGID   int            `xml:"firstgid,attr"`
+	Source     string         `xml:"source,attr,omitempty"`
+	Name       string         `xml:"name,attr,omitempty"`
+	TileWidth  int            `xml:"tilewidth,attr,omitempty"`
+	TileHeight int            `xml:"tileheight,attr,omitempty"`
+	Spacing    int            `xml:"spacing,attr,omitempty"`
+	Margin     int            `xml:"margin,attr,omitempty"`
+	TileCount  int            `xml:"tilecount,attr,omitempty"`
+	Columns    int            `xml:"columns,attr,omitempty"`
+	TileOffset *TileOffset    `xml:"tileoffset"`
+	Grid       *Grid          `xml:"grid"`
+	Properties *xmlProperties `xml:"properties"`
+	Image      *xmlImageOut   `xml:"image"`
+	Terrains   *xmlTerrains   `xml:"terraintypes"`
+	Tiles      []xmlTile      `xml:"tile"`
+}
+
+type xmlMap struct {
+	XMLName       xml.Name       `xml:"map"`
+	Version       string         `xml:"version,attr,omitempty"`
+	TiledVersion  string         `xml:"tiledversion,attr,omitempty"`
+	Orientation   Orientation    `xml:"orientation,attr"`
+	RenderOrder   RenderOrder    `xml:"renderorder,attr,omitempty"`
+	Width         int            `xml:"width,attr"`
+	Height        int            `xml:"height,attr"`
+	TileWidth     int            `xml:"tilewidth,attr"`
+	TileHeight    int            `xml:"tileheight,attr"`
+	StaggerAxis   string         `xml:"staggeraxis,attr,omitempty"`
+	StaggerIndex  string         `xml:"staggerindex,attr,omitempty"`
+	HexSideLength int            `xml:"hexsidelength,attr,omitempty"`
+	Infinite      int            `xml:"infinite,attr"`
+	NextLayerID   int            `xml:"nextlayerid,attr,omitempty"`
+	NextObjectID  int            `xml:"nextobjectid,attr,omitempty"`
+	Properties    *xmlProperties `xml:"properties"`
+	Tilesets      []xmlTileset   `xml:"tileset"`
+	Layers        []xmlLayerOut
+}
+
+func toXMLProperties(props Properties) *xmlProperties {
+	if len(props) == 0 {
+		return nil
+	}
+
+	ret := &xmlProperties{}
+	for _, p := range props {
+		xp := xmlProperty{Name: p.Name, Type: p.Type, Value: fmt.Sprint(p.Value)}
+		if p.Value == nil {
+			xp.Value = ""
+		}
+		if xp.Type == PropertyTypeString {
+			xp.Type = ""
+		}
+		ret.Properties = append(ret.Properties, xp)
+	}
+	return ret
+}
+
+func formatPointList(points []Point) *xmlPoints {
+	pairs := make([]string, len(points))
+	for i, p := range points {
+		pairs[i] = strconv.FormatFloat(p.X, 'g', -1, 64) + "," + strconv.FormatFloat(p.Y, 'g', -1, 64)
+	}
+	return &xmlPoints{Points: strings.Join(pairs, " ")}
+}
+
+func toXMLObject(o Object) xmlObject {
+	xo := xmlObject{
+		ID:         o.ID,
+		Name:       o.Name,
+		Type:       o.Type,
+		GID:        o.GID,
+		X:          o.X,
+		Y:          o.Y,
+		Width:      o.Width,
+		Height:     o.Height,
+		Rotation:   o.Rotation,
+		Template:   o.Template,
+		Properties: toXMLProperties(o.Properties),
+	}
+
+	if !o.Visible {
+		hidden := 0
+		xo.Visible = &hidden
+	}
+
+	if o.Ellipse {
+		xo.Ellipse = &struct{}{}
+	} else if o.Point {
+		xo.Point = &struct{}{}
+	} else if o.Polygon != nil {
+		xo.Polygon = formatPointList(o.Polygon)
+	} else if o.Polyline != nil {
+		xo.Polyline = formatPointList(o.Polyline)
+	}
+
+	return xo
+}
+
+// formatXMLCSV lays out csv data in rows like Tiled does
+func formatXMLCSV(data string, width int) (string, error) {
+	gids, err := parseCSV(data)
+	if err != nil {
+		return "", err
+	}
+
+	if width <= 0 {
+		width = len(gids)
+	}
+
+	var rows []string
+	for begin := 0; begin < len(gids); begin += width {
+		rows = append(rows, formatCSV(gids[begin:min(begin+width, len(gids))]))
+	}
+
+	return "\n" + strings.Join(rows, ",\n") + "\n", nil
+}
+
+func toXMLLayerData(layer Layer) (*xmlLayerData, error) {
+	xd := &xmlLayerData{
+		Encoding:    layer.Encoding,
+		Compression: layer.Compression,
+		Data:        layer.Data,
+	}
+
+	if layer.Encoding == EncodingCSV && layer.Data != "" {
+		var err error
+		if xd.Data, err = formatXMLCSV(layer.Data, layer.WidthInTiles); err != nil {
+			return nil, fmt.Errorf("failed to format csv data of layer '%s': %w", layer.Name, err)
+		}
+	}
+
+	for _, c := range layer.Chunks {
+		xc := xmlDataChunk{X: c.X, Y: c.Y, Width: c.WidthInTiles, Height: c.HeightInTiles, Data: c.Data}
+		if layer.Encoding == EncodingCSV {
+			var err error
+			if xc.Data, err = formatXMLCSV(c.Data, c.WidthInTiles); err != nil {
+				return nil, fmt.Errorf("failed to format csv chunk data of layer '%s': %w", layer.Name, err)
+			}
+		}
+		xd.Chunks = append(xd.Chunks, xc)
+	}
+
+	return xd, nil
+}
+
+func toXMLLayer(layer Layer) (xmlLayerOut, error) {
+	xl := xmlLayerOut{
+		ID:         layer.ID,
+		Name:       layer.Name,
+		OffsetX:    layer.OffsetX,
+		OffsetY:    layer.OffsetY,
+		Properties: toXMLProperties(layer.Properties),
+	}
+
+	if !layer.Visible {
+		hidden := 0
+		xl.Visible = &hidden
+	}
+
+	if layer.Opacity != 1 {
+		opacity := layer.Opacity
+		xl.Opacity = &opacity
+	}
+
+	switch layer.Type {
+	case TileLayer:
+		xl.XMLName.Local = "layer"
+		xl.Width = layer.WidthInTiles
+		xl.Height = layer.HeightInTiles
+
+		var err error
+		if xl.Data, err = toXMLLayerData(layer); err != nil {
+			return xmlLayerOut{}, err
+		}
+
+	case ObjectGroup:
+		xl.XMLName.Local = "objectgroup"
+		if layer.DrawOrder != DrawOrderTopDown {
+			xl.DrawOrder = layer.DrawOrder
+		}
+
+		for _, o := range layer.Objects {
+			xl.Objects = append(xl.Objects, toXMLObject(o))
+		}
+
+	case ImageLayer:
+		xl.XMLName.Local = "imagelayer"
+		xl.Trans = layer.TransparentColor
+		if layer.Image != "" {
+			xl.Image = &xmlImageOut{Source: layer.Image}
+		}
+
+	case Group:
+		xl.XMLName.Local = "group"
+
+		var err error
+		if xl.Layers, err = toXMLLayers(layer.Layers); err != nil {
+			return xmlLayerOut{}, err
+		}
+
+	default:
+		return xmlLayerOut{}, fmt.Errorf("unsupported layer type '%s' of layer '%s'", layer.Type, layer.Name)
+	}
+
+	return xl, nil
+}
+
+func toXMLLayers(layers []Layer) ([]xmlLayerOut, error) {
+	var ret []xmlLayerOut
+	for _, layer := range layers {
+		xl, err := toXMLLayer(layer)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, xl)
+	}
+	return ret, nil
+}
+
+func formatTerrain(terrain TileTerrain) string {
+	corners := make([]string, len(terrain))
+	for i, c := range terrain {
+		if c >= 0 {
+			corners[i] = strconv.Itoa(c)
+		}
+	}
+	return strings.Join(corners, ",")
+}
+
+func toXMLTileset(ts Tileset) (xmlTileset, error) {
+	if ts.Source != "" {
+		return xmlTileset{FirstGID: ts.FirstGID, Source: ts.Source}, nil
+	}
+
+	xts := xmlTileset{
+		FirstGID:   ts.FirstGID,
+		Name:       ts.Name,
+		TileWidth:  ts.TileWidth,
+		TileHeight: ts.TileHeight,
+		Spacing:    ts.Spacing,
+		Margin:     ts.Margin,
+		TileCount:  ts.TileCount,
+		Columns:    ts.Columns,
+		Properties: toXMLProperties(ts.Properties),
+	}
+
+	if ts.TileOffset != (TileOffset{}) {
+		offset := ts.TileOffset
+		xts.TileOffset = &offset
+	}
+
+	if ts.Grid != (Grid{}) {
+		grid := ts.Grid
+		xts.Grid = &grid
+	}
+
+	if ts.Image != "" {
+		xts.Image = &xmlImageOut{Source: ts.Image, Width: ts.ImageWidth, Height: ts.ImageHeight}
+	}
+
+	if len(ts.Terrains) > 0 {
+		xts.Terrains = &xmlTerrains{}
+		for _, t := range ts.Terrains {
+			xts.Terrains.Terrains = append(xts.Terrains.Terrains, xmlTerrain{Name: t.Name, Tile: t.Tile, Properties: toXMLProperties(t.Properties)})
+		}
+	}
+
+	for _, t := range ts.Tiles {
+		xt := xmlTile{
+			ID:          t.ID,
+			Terrain:     formatTerrain(t.Terrain),
+			Probability: t.Probability,
+			Properties:  toXMLProperties(t.Properties),
+		}
+
+		if t.Image != "" {
+			xt.Image = &xmlImageOut{Source: t.Image, Width: t.ImageWidth, Height: t.ImageHeight}
+		}
+
+		if len(t.ObjectGroup.Objects) > 0 {
+			og := t.ObjectGroup
+			og.Type = ObjectGroup
+			xog, err := toXMLLayer(og)
+			if err != nil {
+				return xmlTileset{}, err
+			}
+			xog.XMLName = xml.Name{}
+			xt.ObjectGroup = &xog
+		}
+
+		for _, f := range t.Animation {
+			xt.Animation = append(xt.Animation, xmlFrame{TileID: f.TileID, Duration: f.Duration})
+		}
+
+		xts.Tiles = append(xts.Tiles, xt)
+	}
+
+	return xts, nil
+}
+
+// EncodeXML writes the tilemap as a Tiled TMX (xml) map
+func EncodeXML(w io.Writer, tilemap Tilemap) error {
+	xm := xmlMap{
+		TiledVersion:  tilemap.TiledVersion,
+		Orientation:   tilemap.Orientation,
+		RenderOrder:   tilemap.RenderOrder,
+		Width:         tilemap.WidthInTiles,
+		Height:        tilemap.HeightInTiles,
+		TileWidth:     tilemap.TileWidth,
+		TileHeight:    tilemap.TileHeight,
+		StaggerAxis:   tilemap.StaggerAxis,
+		StaggerIndex:  tilemap.StaggerIndex,
+		HexSideLength: tilemap.HexSideLength,
+		NextLayerID:   tilemap.NextLayerID,
+		NextObjectID:  tilemap.NextObjectID,
+		Properties:    toXMLProperties(tilemap.Properties),
+	}
+
+	if tilemap.Version != 0 {
+		xm.Version = strconv.FormatFloat(tilemap.Version, 'f', -1, 64)
+	}
+
+	if tilemap.Infinite {
+		xm.Infinite = 1
+	}
+
+	for _, ts := range tilemap.Tilesets {
+		xts, err := toXMLTileset(ts)
+		if err != nil {
+			return fmt.Errorf("failed to encode tileset '%s': %w", ts.Name, err)
+		}
+		xm.Tilesets = append(xm.Tilesets, xts)
+	}
+
+	var err error
+	if xm.Layers, err = toXMLLayers(tilemap.Layers); err != nil {
+		return fmt.Errorf("failed to encode layers: %w", err)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", " ")
+	if err := encoder.Encode(&xm); err != nil {
+		return fmt.Errorf("failed to xml encode: %w", err)
+	}
+
+	_, err = io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,69 @@
+package tmsplit
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestEncodeXMLRoundTrip(t *testing.T) {
+	for _, name := range []string{"testdata/finite.tmx", "testdata/infinite.tmx"} {
+		t.Run(name, func(t *testing.T) {
+			tm := parseFixture(t, name)
+
+			var buf bytes.Buffer
+			if err := EncodeXML(&buf, tm); err != nil {
+				t.Fatalf("failed to encode: %v", err)
+			}
+			decoded, err := ParseXML(&buf)
+			if err != nil {
+				t.Fatalf("failed to parse encoded map: %v\n%s", err, buf.String())
+			}
+
+			if !reflect.DeepEqual(decoded, tm) {
+				t.Errorf("got\n%+v\nwant\n%+v", decoded, tm)
+			}
+		})
+	}
+}
+
+// the round trip keeps what Tiled leaves out of the tmx when it has the default value
+func TestEncodeXMLRoundTripDetails(t *testing.T) {
+	tm := parseFixture(t, "testdata/finite.tmx")
+
+	var buf bytes.Buffer
+	if err := EncodeXML(&buf, tm); err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	decoded, err := ParseXML(&buf)
+	if err != nil {
+		t.Fatalf("failed to parse encoded map: %v", err)
+	}
+
+	if len(decoded.Properties) != 4 || decoded.Properties[1].Value != int64(3) || decoded.Properties[2].Value != true {
+		t.Errorf("got map properties %+v", decoded.Properties)
+	}
+
+	group := decoded.Layers[1]
+	if group.Type != Group || group.Opacity != 0.5 || !group.Visible || len(group.Layers) != 1 {
+		t.Errorf("got group %s '%s' opacity %g visible %t with %d layers", group.Type, group.Name, group.Opacity, group.Visible, len(group.Layers))
+	} else if hidden := group.Layers[0]; hidden.Visible || hidden.Opacity != 1 {
+		t.Errorf("got layer '%s' visible %t opacity %g in group", hidden.Name, hidden.Visible, hidden.Opacity)
+	}
+
+	gid, err := decoded.Layers[0].TileAt(2, 0)
+	if err != nil {
+		t.Fatalf("failed to get tile: %v", err)
+	}
+	if gid != NewGID(3, true, false, false) {
+		t.Errorf("got gid %d, want 3 flipped horizontally", gid)
+	}
+
+	objects := decoded.Layers[2].Objects
+	if !pointsEqual(objects[1].Polygon, []Point{{0, 0}, {16, 0}, {8, -12}}) {
+		t.Errorf("got polygon %v", objects[1].Polygon)
+	}
+	if objects[6].Visible || objects[6].Rotation != 45 {
+		t.Errorf("got object '%s' visible %t rotation %g", objects[6].Name, objects[6].Visible, objects[6].Rotation)
+	}
+}
@@ -1,6 +1,7 @@
 package tmsplit
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"text/template"
@@ -8,8 +9,10 @@ import (
 
 var TypescriptTemplate = `
 {{- range $i, $e := .Tilemaps -}}
+{{- if not $e.Empty -}}
 import t{{$i}} from '../../static/{{ $e.URL }}';
 {{ end -}}
+{{ end -}}
 
 {{ range $i, $e := .Tilesets -}}
 import s{{$i}} from '../../static/{{ $e.SpritesheetURL }}';
@@ -17,15 +20,26 @@ import s{{$i}} from '../../static/{{ $e.SpritesheetURL }}';
 
 const map = {
     spawn: { x: {{.Spawn.X}}, y: {{.Spawn.Y}} },
+    deduplicated: {{ .Deduplicated }},
     tilemaps: [
         {{- range $i, $e := .Tilemaps }}
         {
             key: '{{ $e.Key }}',
+            {{- if $e.Empty }}
+            url: '',
+            empty: true,
+            {{- else }}
             url: (t{{ $i }} as unknown) as string,
+            {{- end }}
             tileX: {{ $e.TileX }},
             tileY: {{ $e.TileY }},
+            pixelX: {{ $e.PixelX }},
+            pixelY: {{ $e.PixelY }},
             widthInTiles: {{ $e.WidthInTiles }},
             heightInTiles: {{ $e.HeightInTiles }},
+            {{- with $e.Padding }}
+            padding: { left: {{ .Left }}, top: {{ .Top }}, right: {{ .Right }}, bottom: {{ .Bottom }} },
+            {{- end }}
         },
         {{- end }}
     ],
@@ -40,19 +54,29 @@ const map = {
         },
         {{- end }}
     ],
+    objects: {
+        {{- range $id, $keys := .Objects }}
+        {{ $id }}: [{{ range $i, $key := $keys }}{{ if $i }}, {{ end }}'{{ $key }}'{{ end }}],
+        {{- end }}
+    },
 };
 
 export { map };
 `
 
 func FormatTypescript(w io.Writer, master MasterFile) error {
+	return FormatTypescriptContext(context.Background(), w, master)
+}
+
+// FormatTypescriptContext is FormatTypescript that stops writing when the context is done
+func FormatTypescriptContext(ctx context.Context, w io.Writer, master MasterFile) error {
 	templ, err := template.New("").Parse(TypescriptTemplate)
 	if err != nil {
 		return fmt.Errorf("failed to parse template: %w", err)
 	}
 
-	if err := templ.Execute(w, master); err != nil {
-		return fmt.Errorf("failed to execute template: %v", err)
+	if err := templ.Execute(contextWriter{ctx, "format typescript", w}, master); err != nil {
+		return fmt.Errorf("failed to execute template: %w", err)
 	}
 	return nil
 }
@@ -0,0 +1,38 @@
+package tmsplit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestFormatTypescriptCanceled(t *testing.T) {
+	tm := parseFixture(t, "testdata/finite.tmx")
+	chunks, err := Split(tm, SplitOptions{ChunkWidth: 2, ChunkHeight: 3})
+	if err != nil {
+		t.Fatalf("failed to split: %v", err)
+	}
+	master, err := CreateMasterFile(chunks, "finite.tmx")
+	if err != nil {
+		t.Fatalf("failed to create master file: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var buf bytes.Buffer
+	err = FormatTypescriptContext(ctx, &buf, master)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want %v", err, context.Canceled)
+	}
+
+	var canceled *CanceledError
+	if !errors.As(err, &canceled) {
+		t.Errorf("got error %T, want %T", err, canceled)
+	}
+
+	if err := FormatTypescript(&buf, master); err != nil {
+		t.Errorf("failed to format: %v", err)
+	}
+}
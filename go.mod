@@ -1,8 +1,9 @@
 module github.com/codename-pyoko/tmsplit
 
-go 1.14
+go 1.16
 
 require (
+	github.com/klauspost/compress v1.11.13
 	github.com/kr/text v0.2.0 // indirect
 	github.com/niemeyer/pretty v0.0.0-20200227124842-a10e7caefd8e // indirect
 	github.com/sirupsen/logrus v1.6.0
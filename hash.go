@@ -0,0 +1,28 @@
+package tmsplit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// HashChunk returns a hash of the content of a chunk: its size, layers with their data and objects,
+// properties, tilesets and stagger. Chunks with the same hash can share a single file.
+func HashChunk(tm Tilemap) (string, error) {
+	content := struct {
+		WidthInTiles  int        `json:"width"`
+		HeightInTiles int        `json:"height"`
+		Layers        []Layer    `json:"layers"`
+		Properties    Properties `json:"properties"`
+		Tilesets      []Tileset  `json:"tilesets"`
+		StaggerIndex  string     `json:"staggerindex,omitempty"`
+	}{tm.WidthInTiles, tm.HeightInTiles, tm.Layers, tm.Properties, tm.Tilesets, tm.StaggerIndex}
+
+	h := sha256.New()
+	if err := json.NewEncoder(h).Encode(&content); err != nil {
+		return "", fmt.Errorf("failed to encode chunk for hashing: %w", err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
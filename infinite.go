@@ -0,0 +1,122 @@
+package tmsplit
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+type tileBounds struct {
+	minX, minY, maxX, maxY int
+	empty                  bool
+}
+
+func (b *tileBounds) add(c Chunk) {
+	if b.empty {
+		*b = tileBounds{minX: c.X, minY: c.Y, maxX: c.X + c.WidthInTiles, maxY: c.Y + c.HeightInTiles}
+		return
+	}
+
+	if c.X < b.minX {
+		b.minX = c.X
+	}
+	if c.Y < b.minY {
+		b.minY = c.Y
+	}
+	if c.X+c.WidthInTiles > b.maxX {
+		b.maxX = c.X + c.WidthInTiles
+	}
+	if c.Y+c.HeightInTiles > b.maxY {
+		b.maxY = c.Y + c.HeightInTiles
+	}
+}
+
+func chunkBounds(layers []Layer, bounds *tileBounds) {
+	for _, layer := range layers {
+		for _, c := range layer.Chunks {
+			bounds.add(c)
+		}
+		chunkBounds(layer.Layers, bounds)
+	}
+}
+
+func normalizeLayers(layers []Layer, bounds tileBounds, tilemap Tilemap) error {
+	width := bounds.maxX - bounds.minX
+	height := bounds.maxY - bounds.minY
+
+	for layerIndex := range layers {
+		layer := &layers[layerIndex]
+
+		switch layer.Type {
+		case TileLayer:
+			data := make([]GID, width*height)
+			for _, c := range layer.Chunks {
+				chunkData, err := layer.chunkTiles(c)
+				if err != nil {
+					return err
+				}
+
+				for row := 0; row < c.HeightInTiles; row++ {
+					begin := (c.Y-bounds.minY+row)*width + c.X - bounds.minX
+					copy(data[begin:begin+c.WidthInTiles], chunkData[row*c.WidthInTiles:(row+1)*c.WidthInTiles])
+				}
+			}
+
+			encoded, err := encodeLayerData(data, layer.Encoding, layer.Compression)
+			if err != nil {
+				return fmt.Errorf("failed to encode layer '%s': %w", layer.Name, err)
+			}
+
+			layer.Data = encoded
+			layer.Chunks = nil
+			layer.WidthInTiles = width
+			layer.HeightInTiles = height
+			layer.StartX = 0
+			layer.StartY = 0
+
+		case ObjectGroup:
+			offset := tileToObject(bounds.minX, bounds.minY, tilemap)
+			for objectIndex := range layer.Objects {
+				layer.Objects[objectIndex].X -= offset.X
+				layer.Objects[objectIndex].Y -= offset.Y
+			}
+
+		case Group:
+			if err := normalizeLayers(layer.Layers, bounds, tilemap); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// NormalizeInfinite turns the chunked layers of an infinite map into a bounded map covering all chunks.
+// The tile position of the new top left corner in the infinite map is recorded in OriginX and OriginY.
+func NormalizeInfinite(tilemap Tilemap) (Tilemap, error) {
+	if !tilemap.Infinite {
+		return tilemap, nil
+	}
+
+	bounds := tileBounds{empty: true}
+	chunkBounds(tilemap.Layers, &bounds)
+	if bounds.empty {
+		bounds = tileBounds{}
+	}
+	logrus.Debugf("infinite map bounds: %d,%d - %d,%d", bounds.minX, bounds.minY, bounds.maxX, bounds.maxY)
+
+	tm := tilemap
+	tm.Layers = cloneLayers(tilemap.Layers)
+	if err := normalizeLayers(tm.Layers, bounds, tm); err != nil {
+		return Tilemap{}, fmt.Errorf("failed to normalize layers: %w", err)
+	}
+
+	tm.Infinite = false
+	tm.WidthInTiles = bounds.maxX - bounds.minX
+	tm.HeightInTiles = bounds.maxY - bounds.minY
+	tm.OriginX = tilemap.OriginX + bounds.minX
+	tm.OriginY = tilemap.OriginY + bounds.minY
+	shiftStagger(&tm, bounds.minX, bounds.minY)
+
+	return tm, nil
+}
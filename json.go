@@ -0,0 +1,164 @@
+package tmsplit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// unmarshalLayerDataJSON accepts layer data both as a base64 string and as the array of gids
+// Tiled writes for csv encoded layers, returning array data in csv form
+func unmarshalLayerDataJSON(raw json.RawMessage) (string, bool, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return "", false, nil
+	}
+
+	if raw[0] == '[' {
+		var gids []GID
+		if err := json.Unmarshal(raw, &gids); err != nil {
+			return "", false, fmt.Errorf("failed to decode csv layer data: %w", err)
+		}
+		return formatCSV(gids), true, nil
+	}
+
+	var data string
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return "", false, fmt.Errorf("failed to decode layer data: %w", err)
+	}
+	return data, false, nil
+}
+
+func (l *Layer) UnmarshalJSON(b []byte) error {
+	type alias Layer
+	buf := struct {
+		*alias
+		Data   json.RawMessage   `json:"data"`
+		Chunks []json.RawMessage `json:"chunks"`
+	}{alias: (*alias)(l)}
+
+	// defaults for when the fields are missing
+	l.Opacity = 1
+	l.Visible = true
+
+	if err := json.Unmarshal(b, &buf); err != nil {
+		return err
+	}
+
+	data, isArray, err := unmarshalLayerDataJSON(buf.Data)
+	if err != nil {
+		return err
+	}
+	l.Data = data
+
+	l.Chunks = nil
+	for _, raw := range buf.Chunks {
+		var chunk Chunk
+		chunkIsArray, err := unmarshalChunkJSON(raw, &chunk)
+		if err != nil {
+			return fmt.Errorf("failed to decode chunk %d,%d: %w", chunk.X, chunk.Y, err)
+		}
+		isArray = isArray || chunkIsArray
+		l.Chunks = append(l.Chunks, chunk)
+	}
+
+	if isArray && l.Encoding == "" {
+		// Tiled omits the encoding for csv layers, including the chunks of infinite maps
+		l.Encoding = EncodingCSV
+	}
+
+	return nil
+}
+
+// unmarshalChunkJSON decodes a chunk of an infinite layer, reporting if its data was an array of gids
+func unmarshalChunkJSON(b []byte, c *Chunk) (bool, error) {
+	type alias Chunk
+	buf := struct {
+		*alias
+		Data json.RawMessage `json:"data"`
+	}{alias: (*alias)(c)}
+
+	if err := json.Unmarshal(b, &buf); err != nil {
+		return false, err
+	}
+
+	data, isArray, err := unmarshalLayerDataJSON(buf.Data)
+	if err != nil {
+		return false, err
+	}
+
+	c.Data = data
+	return isArray, nil
+}
+
+func (c *Chunk) UnmarshalJSON(b []byte) error {
+	_, err := unmarshalChunkJSON(b, c)
+	return err
+}
+
+func (l Layer) MarshalJSON() ([]byte, error) {
+	type alias Layer
+	if l.Encoding != EncodingCSV {
+		return json.Marshal(alias(l))
+	}
+
+	type csvChunk struct {
+		Chunk
+		Data []GID `json:"data"`
+	}
+
+	var chunks []csvChunk
+	for _, c := range l.Chunks {
+		gids, err := parseCSV(c.Data)
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, csvChunk{c, gids})
+	}
+
+	var gids []GID
+	if l.Data != "" {
+		var err error
+		if gids, err = parseCSV(l.Data); err != nil {
+			return nil, err
+		}
+	}
+
+	return json.Marshal(struct {
+		alias
+		Chunks []csvChunk `json:"chunks,omitempty"`
+		Data   []GID      `json:"data,omitempty"`
+	}{alias(l), chunks, gids})
+}
+
+func (ts Tileset) MarshalJSON() ([]byte, error) {
+	type alias Tileset
+	if ts.Source == "" {
+		return json.Marshal(alias(ts))
+	}
+
+	// referenced tilesets are written as references, even when they've been resolved
+	return json.Marshal(struct {
+		FirstGID int    `json:"firstgid"`
+		Source   string `json:"source"`
+	}{ts.FirstGID, ts.Source})
+}
+
+func (p *Property) UnmarshalJSON(b []byte) error {
+	type alias Property
+	if err := json.Unmarshal(b, (*alias)(p)); err != nil {
+		return err
+	}
+
+	// match the value types ParseXML produces
+	switch p.Type {
+	case "":
+		p.Type = PropertyTypeString
+	case PropertyTypeInt:
+		if f, ok := p.Value.(float64); ok {
+			p.Value = int64(f)
+		}
+	}
+
+	return nil
+}
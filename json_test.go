@@ -0,0 +1,154 @@
+package tmsplit
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const csvJSONMap = `{
+	"width": 2, "height": 2, "tilewidth": 16, "tileheight": 16,
+	"orientation": "orthogonal", "renderorder": "right-down", "type": "map", "version": 1.4,
+	"infinite": false, "nextlayerid": 2, "nextobjectid": 1,
+	"layers": [
+		{"id": 1, "name": "ground", "type": "tilelayer", "width": 2, "height": 2, "x": 0, "y": 0,
+		 "opacity": 1, "visible": true, "data": [1, 2, 2147483651, 0]}
+	],
+	"tilesets": [
+		{"firstgid": 1, "name": "ts", "image": "ts.png", "imagewidth": 64, "imageheight": 64,
+		 "tilewidth": 16, "tileheight": 16, "tilecount": 16, "columns": 4, "margin": 0, "spacing": 0}
+	]
+}`
+
+func TestCSVArrayRoundTrip(t *testing.T) {
+	tm, err := ParseJSON(strings.NewReader(csvJSONMap))
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	layer := tm.Layers[0]
+	if layer.Encoding != EncodingCSV {
+		t.Errorf("got encoding '%s', want '%s'", layer.Encoding, EncodingCSV)
+	}
+
+	grid, err := layer.Tiles()
+	if err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	want := []GID{1, 2, NewGID(3, true, false, false), 0}
+	if !reflect.DeepEqual(grid.GIDs, want) {
+		t.Errorf("got %v, want %v", grid.GIDs, want)
+	}
+
+	b, err := json.Marshal(&tm)
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+
+	var out struct {
+		Layers []struct {
+			Encoding string   `json:"encoding"`
+			Data     []uint32 `json:"data"`
+		} `json:"layers"`
+	}
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatalf("failed to read output %s: %v", b, err)
+	}
+
+	if out.Layers[0].Encoding != "csv" {
+		t.Errorf("got output encoding '%s', want 'csv'", out.Layers[0].Encoding)
+	}
+	if !reflect.DeepEqual(out.Layers[0].Data, []uint32{1, 2, 2147483651, 0}) {
+		t.Errorf("got output data %v", out.Layers[0].Data)
+	}
+
+	again, err := ParseJSON(strings.NewReader(string(b)))
+	if err != nil {
+		t.Fatalf("failed to parse output: %v", err)
+	}
+	if !reflect.DeepEqual(again, tm) {
+		t.Errorf("tilemap changed in round trip:\ngot  %+v\nwant %+v", again, tm)
+	}
+}
+
+func TestCSVEncodingOption(t *testing.T) {
+	tm, err := ParseJSON(strings.NewReader(csvJSONMap))
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	// base64 source layers are written as csv arrays when asked to
+	base64 := EncodingBase64
+	opts := SplitOptions{ChunkWidth: 1, ChunkHeight: 2, Encoding: &base64}
+	chunks, err := Split(tm, opts)
+	if err != nil {
+		t.Fatalf("failed to split: %v", err)
+	}
+
+	csv := EncodingCSV
+	opts.Encoding = &csv
+	tm = chunks[0]
+	chunks, err = Split(tm, opts)
+	if err != nil {
+		t.Fatalf("failed to split: %v", err)
+	}
+
+	b, err := json.Marshal(&chunks[0])
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+	if !strings.Contains(string(b), `"data":[1,2147483651]`) || !strings.Contains(string(b), `"encoding":"csv"`) {
+		t.Errorf("chunk not written as csv array: %s", b)
+	}
+}
+
+// Tiled writes the chunks of infinite json maps as arrays without an encoding
+func TestSplitInfiniteJSONWithoutEncoding(t *testing.T) {
+	f, err := os.Open("testdata/infinite.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	tm, err := ParseJSON(f)
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	if tm.Layers[0].Encoding != EncodingCSV {
+		t.Errorf("got encoding '%s', want '%s'", tm.Layers[0].Encoding, EncodingCSV)
+	}
+	if tm.Layers[1].Layers[0].Encoding != EncodingCSV {
+		t.Errorf("got group layer encoding '%s', want '%s'", tm.Layers[1].Layers[0].Encoding, EncodingCSV)
+	}
+
+	chunks, err := Split(tm, SplitOptions{ChunkWidth: 2, ChunkHeight: 2})
+	if err != nil {
+		t.Fatalf("failed to split: %v", err)
+	}
+
+	want := map[[2]int][]GID{
+		{-4, -4}: {1, 2, 3, 4},
+		{0, 0}:   {5, 6, 7, NewGID(8, true, false, false)},
+	}
+	for _, chunk := range chunks {
+		tiles, ok := want[[2]int{chunk.OriginX, chunk.OriginY}]
+		if !ok {
+			continue
+		}
+		delete(want, [2]int{chunk.OriginX, chunk.OriginY})
+
+		grid, err := chunk.Layers[0].Tiles()
+		if err != nil {
+			t.Fatalf("failed to decode chunk %d,%d: %v", chunk.OriginX, chunk.OriginY, err)
+		}
+		if !reflect.DeepEqual(grid.GIDs, tiles) {
+			t.Errorf("chunk %d,%d: got %v, want %v", chunk.OriginX, chunk.OriginY, grid.GIDs, tiles)
+		}
+	}
+	for origin := range want {
+		t.Errorf("no chunk at %d,%d", origin[0], origin[1])
+	}
+}
@@ -1,18 +1,27 @@
 package tmsplit
 
 import (
+	"context"
 	"fmt"
 	"path"
 	"strings"
 )
 
 type MasterTilemapEntry struct {
-	Key           string `json:"key"`
-	URL           string `json:"url"`
-	TileX         int    `json:"tileX"`
-	TileY         int    `json:"tileY"`
-	WidthInTiles  int    `json:"widthInTiles"`
-	HeightInTiles int    `json:"heightInTiles"`
+	Key   string `json:"key"`
+	URL   string `json:"url"`
+	TileX int    `json:"tileX"`
+	TileY int    `json:"tileY"`
+	// PixelX and PixelY is the rendered position of the top left corner of the chunk bounds,
+	// relative to the rendered top left corner of tile 0,0, which is its top corner on isometric maps
+	PixelX        int `json:"pixelX"`
+	PixelY        int `json:"pixelY"`
+	WidthInTiles  int `json:"widthInTiles"`
+	HeightInTiles int `json:"heightInTiles"`
+	// Padding is the part of the chunk, from TileX and TileY, borrowed from neighbouring chunks
+	Padding *Padding `json:"padding,omitempty"`
+	// Empty chunks have no tiles or objects and no URL
+	Empty bool `json:"empty,omitempty"`
 }
 
 type MasterTileset struct {
@@ -23,6 +32,7 @@ type MasterTileset struct {
 	TilesetKey     string `json:"tilesetKey"`
 }
 
+// Spawn is the rendered position of the spawn object, relative to the rendered top left corner of tile 0,0
 type Spawn struct {
 	X int `json:"x"`
 	Y int `json:"y"`
@@ -32,6 +42,15 @@ type MasterFile struct {
 	Spawn    Spawn                `json:"spawn"`
 	Tilesets []MasterTileset      `json:"tilesets"`
 	Tilemaps []MasterTilemapEntry `json:"tilemaps"`
+	// Deduplicated is the number of tilemaps that share the url of an earlier tilemap with the same content
+	Deduplicated int `json:"deduplicated"`
+	// Objects lists the keys of the tilemaps each object of the source map is in, by the id of the object in the source map
+	Objects map[int][]string `json:"objects,omitempty"`
+	// Source describes the source map, whose bounds can reach beyond the tilemaps when empty chunks are skipped
+	Source *SourceMap `json:"source,omitempty"`
+
+	// url of the first tilemap added for each hash
+	hashURLs map[string]string
 }
 
 func containsTileset(tilesets []MasterTileset, spritesheetKey string) bool {
@@ -43,60 +62,118 @@ func containsTileset(tilesets []MasterTileset, spritesheetKey string) bool {
 	return false
 }
 
-func CreateMasterFile(tilemaps []Tilemap, sourceFileBase string, nChunksWidth int) (MasterFile, error) {
-	var mtilesets []MasterTileset
-	var mtilemaps []MasterTilemapEntry
-	var spawn Spawn
-	for tmindex, tm := range tilemaps {
-		for _, ts := range tm.Tilesets {
-			spritesheetKey := fmt.Sprintf("spritesheet-%s", ts.Name)
-			if containsTileset(mtilesets, spritesheetKey) {
-				continue
-			}
-
-			mts := MasterTileset{
-				SpritesheetKey: spritesheetKey,
-				TilesetKey:     ts.Name,
-				FrameWidth:     ts.TileWidth,
-				FrameHeight:    ts.TileHeight,
-				SpritesheetURL: path.Base(ts.Image),
-			}
+// collectObjects returns the objects of all object groups, including those nested in groups
+func collectObjects(layers []Layer) []Object {
+	var objects []Object
+	for _, l := range layers {
+		switch l.Type {
+		case ObjectGroup:
+			objects = append(objects, l.Objects...)
+		case Group:
+			objects = append(objects, collectObjects(l.Layers)...)
+		}
+	}
+	return objects
+}
 
-			mtilesets = append(mtilesets, mts)
+// AddTilemap adds a chunk to the master file, with the key and url derived from its index.
+// urlFmt is the url of the chunk files relative to the master file, with %d for the index,
+// or empty for the json files named after the source file.
+// Chunks with the same Hash as an earlier chunk get the url of the earlier chunk.
+func (m *MasterFile) AddTilemap(tmindex int, tm Tilemap, sourceFileBase, urlFmt string) {
+	for _, ts := range tm.Tilesets {
+		spritesheetKey := fmt.Sprintf("spritesheet-%s", ts.Name)
+		if containsTileset(m.Tilesets, spritesheetKey) {
+			continue
 		}
 
-		noExt := strings.TrimSuffix(sourceFileBase, path.Ext(sourceFileBase))
-		mtm := MasterTilemapEntry{
-			Key:           fmt.Sprintf("%s-%d", noExt, tmindex),
-			URL:           fmt.Sprintf("%s-%d.json", noExt, tmindex),
-			HeightInTiles: tm.HeightInTiles,
-			WidthInTiles:  tm.WidthInTiles,
-			TileX:         tmindex % nChunksWidth * tm.WidthInTiles,
-			TileY:         tmindex / nChunksWidth * tm.HeightInTiles,
+		mts := MasterTileset{
+			SpritesheetKey: spritesheetKey,
+			TilesetKey:     ts.Name,
+			FrameWidth:     ts.TileWidth,
+			FrameHeight:    ts.TileHeight,
+			SpritesheetURL: path.Base(ts.Image),
 		}
 
-		for _, l := range tm.Layers {
-			if l.Type != ObjectGroup {
-				continue
+		m.Tilesets = append(m.Tilesets, mts)
+	}
+
+	noExt := strings.TrimSuffix(sourceFileBase, path.Ext(sourceFileBase))
+	if urlFmt == "" {
+		urlFmt = strings.ReplaceAll(noExt, "%", "%%") + "-%d.json"
+	}
+
+	mtm := MasterTilemapEntry{
+		Key:           fmt.Sprintf("%s-%d", noExt, tmindex),
+		URL:           fmt.Sprintf(urlFmt, tmindex),
+		HeightInTiles: tm.HeightInTiles,
+		WidthInTiles:  tm.WidthInTiles,
+		TileX:         tm.OriginX,
+		TileY:         tm.OriginY,
+	}
+	mtm.PixelX, mtm.PixelY = chunkPixelOrigin(tm.OriginX, tm.OriginY, tm.HeightInTiles, tm)
+
+	if tm.Empty {
+		mtm.URL = ""
+		mtm.Empty = true
+	} else if tm.Hash != "" {
+		if url, ok := m.hashURLs[tm.Hash]; ok {
+			mtm.URL = url
+			m.Deduplicated++
+		} else {
+			if m.hashURLs == nil {
+				m.hashURLs = map[string]string{}
 			}
+			m.hashURLs[tm.Hash] = mtm.URL
+		}
+	}
+
+	if m.Source == nil && tm.Source != (SourceMap{}) {
+		source := tm.Source
+		m.Source = &source
+	}
+
+	if tm.Padding != (Padding{}) {
+		padding := tm.Padding
+		mtm.Padding = &padding
+	}
 
-			for _, o := range l.Objects {
-				if o.Type != "spawn" {
-					continue
-				}
-				if spawn.X == 0 && spawn.Y == 0 || o.Properties.HasProperty("type", "primary") {
-					spawn.X = mtm.TileX*tm.TileWidth + int(o.X)
-					spawn.Y = mtm.TileY*tm.TileHeight + int(o.Y)
-				}
+	for _, o := range collectObjects(tm.Layers) {
+		id := o.OriginID()
+		if keys := m.Objects[id]; len(keys) == 0 || keys[len(keys)-1] != mtm.Key {
+			if m.Objects == nil {
+				m.Objects = map[int][]string{}
 			}
+			m.Objects[id] = append(keys, mtm.Key)
 		}
 
-		mtilemaps = append(mtilemaps, mtm)
+		if o.Type != "spawn" {
+			continue
+		}
+		if m.Spawn.X == 0 && m.Spawn.Y == 0 || o.Properties.HasProperty("type", "primary") {
+			origin := tileToObject(mtm.TileX, mtm.TileY, tm)
+			spawn := objectToPixel(Point{origin.X + o.X, origin.Y + o.Y}, tm)
+			m.Spawn.X = int(spawn.X)
+			m.Spawn.Y = int(spawn.Y)
+		}
+	}
+
+	m.Tilemaps = append(m.Tilemaps, mtm)
+}
+
+func CreateMasterFile(tilemaps []Tilemap, sourceFileBase string) (MasterFile, error) {
+	return CreateMasterFileContext(context.Background(), tilemaps, sourceFileBase)
+}
+
+// CreateMasterFileContext is CreateMasterFile that stops between tilemaps when the context is done
+func CreateMasterFileContext(ctx context.Context, tilemaps []Tilemap, sourceFileBase string) (MasterFile, error) {
+	var master MasterFile
+	for tmindex, tm := range tilemaps {
+		if err := checkContext(ctx, "create master file"); err != nil {
+			return MasterFile{}, err
+		}
+		master.AddTilemap(tmindex, tm, sourceFileBase, "")
 	}
 
-	return MasterFile{
-		Spawn:    spawn,
-		Tilesets: mtilesets,
-		Tilemaps: mtilemaps,
-	}, nil
+	return master, nil
 }
@@ -0,0 +1,67 @@
+package tmsplit
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMasterFileURLs(t *testing.T) {
+	tm := parseFixture(t, "testdata/finite.tmx")
+	chunks, err := Split(tm, SplitOptions{ChunkWidth: 2, ChunkHeight: 3})
+	if err != nil {
+		t.Fatalf("failed to split: %v", err)
+	}
+
+	tests := []struct {
+		urlFmt string
+		want   []string
+	}{
+		{"", []string{"finite-0.json", "finite-1.json"}},
+		{"finite-%d.tmx", []string{"finite-0.tmx", "finite-1.tmx"}},
+		{"chunks/part-%d.json", []string{"chunks/part-0.json", "chunks/part-1.json"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.urlFmt, func(t *testing.T) {
+			var master MasterFile
+			for i, chunk := range chunks {
+				master.AddTilemap(i, chunk, "finite.tmx", tt.urlFmt)
+			}
+
+			if len(master.Tilemaps) != len(tt.want) {
+				t.Fatalf("got %d tilemaps, want %d", len(master.Tilemaps), len(tt.want))
+			}
+			for i, entry := range master.Tilemaps {
+				if entry.URL != tt.want[i] {
+					t.Errorf("tilemap %d: got url '%s', want '%s'", i, entry.URL, tt.want[i])
+				}
+				if key := fmt.Sprintf("finite-%d", i); entry.Key != key {
+					t.Errorf("tilemap %d: got key '%s', want '%s'", i, entry.Key, key)
+				}
+			}
+		})
+	}
+}
+
+func TestMasterFileDuplicateURL(t *testing.T) {
+	tm := parseFixture(t, "testdata/finite.tmx")
+	tm.Layers = tm.Layers[:1]
+	chunks, err := Split(tm, SplitOptions{ChunkWidth: 1, ChunkHeight: 1, Deduplicate: true})
+	if err != nil {
+		t.Fatalf("failed to split: %v", err)
+	}
+
+	// the same chunk twice shares the url of the first
+	chunks = append(chunks[:1], chunks[0])
+	var master MasterFile
+	for i, chunk := range chunks {
+		master.AddTilemap(i, chunk, "finite.tmx", "finite-%d.tmx")
+	}
+
+	if master.Deduplicated != 1 {
+		t.Errorf("got %d deduplicated, want 1", master.Deduplicated)
+	}
+	if master.Tilemaps[1].URL != "finite-0.tmx" {
+		t.Errorf("got url '%s', want 'finite-0.tmx'", master.Tilemaps[1].URL)
+	}
+}
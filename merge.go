@@ -0,0 +1,346 @@
+package tmsplit
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// tilesetKey identifies the same tileset across chunks
+func tilesetKey(ts Tileset) string {
+	if ts.Source != "" {
+		return "source:" + ts.Source
+	}
+	return "name:" + ts.Name
+}
+
+// mergedTileset is a tileset of the merged map with the number of gids it needs
+type mergedTileset struct {
+	tileset Tileset
+	count   int
+}
+
+// mergeTilesets collects the tilesets of all chunks once, in order of appearance, and numbers their gids from 1
+func mergeTilesets(chunks []Tilemap, master MasterFile) ([]Tileset, map[string]int) {
+	var merged []mergedTileset
+	indices := map[string]int{}
+
+	for chunkIndex, chunk := range chunks {
+		if master.Tilemaps[chunkIndex].Empty {
+			continue
+		}
+
+		tilesets := append([]Tileset(nil), chunk.Tilesets...)
+		sort.SliceStable(tilesets, func(i, j int) bool { return tilesets[i].FirstGID < tilesets[j].FirstGID })
+
+		for i, ts := range tilesets {
+			count := tilesetSpan(ts, nextFirstGID(tilesets, i))
+
+			key := tilesetKey(ts)
+			if mi, ok := indices[key]; ok {
+				if count > merged[mi].count {
+					merged[mi].count = count
+				}
+				continue
+			}
+
+			indices[key] = len(merged)
+			merged = append(merged, mergedTileset{tileset: ts.Clone(), count: count})
+		}
+	}
+
+	tilesets := make([]Tileset, len(merged))
+	firstgid := 1
+	for i, m := range merged {
+		tilesets[i] = m.tileset
+		tilesets[i].FirstGID = firstgid
+		firstgid += m.count
+	}
+
+	return tilesets, indices
+}
+
+// chunkRemap returns a function moving the gids of a chunk to the gids of the merged tilesets
+func chunkRemap(chunk Tilemap, merged []Tileset, indices map[string]int) func(GID) GID {
+	tilesets := append([]Tileset(nil), chunk.Tilesets...)
+	sort.SliceStable(tilesets, func(i, j int) bool { return tilesets[i].FirstGID < tilesets[j].FirstGID })
+
+	offsets := make([]int, len(tilesets))
+	for i, ts := range tilesets {
+		offsets[i] = merged[indices[tilesetKey(ts)]].FirstGID - ts.FirstGID
+	}
+
+	return func(gid GID) GID {
+		i := tilesetIndex(tilesets, gid)
+		if i < 0 {
+			return gid
+		}
+		return gid.WithTileID(uint32(int(gid.TileID()) + offsets[i]))
+	}
+}
+
+// mergeLayers copies the tiles a chunk owns and all its objects into the merged layers
+func mergeLayers(layers []Layer, decoded []decodedLayer, chunkLayers []Layer, tilemap Tilemap, entry MasterTilemapEntry, owned chunkRegion, remap func(GID) GID) error {
+	if len(layers) != len(chunkLayers) {
+		return fmt.Errorf("chunk has %d layers, expected %d", len(chunkLayers), len(layers))
+	}
+
+	for layerIndex := range layers {
+		layer := &layers[layerIndex]
+		chunkLayer := chunkLayers[layerIndex]
+		if chunkLayer.Name != layer.Name || chunkLayer.Type != layer.Type {
+			return fmt.Errorf("chunk layer '%s' doesn't match layer '%s'", chunkLayer.Name, layer.Name)
+		}
+
+		switch layer.Type {
+		case TileLayer:
+			grid, err := chunkLayer.Tiles()
+			if err != nil {
+				return err
+			}
+
+			for y := owned.top; y < owned.top+owned.height; y++ {
+				for x := owned.left; x < owned.left+owned.width; x++ {
+					gid := grid.At(x-entry.TileX, y-entry.TileY)
+					decoded[layerIndex].data[(y-tilemap.OriginY)*tilemap.WidthInTiles+x-tilemap.OriginX] = remap(gid)
+				}
+			}
+
+		case ObjectGroup:
+			for _, object := range chunkLayer.Objects {
+				object = object.Clone()
+				object.ID = object.OriginID()
+				offset := tileToObject(entry.TileX-tilemap.OriginX, entry.TileY-tilemap.OriginY, tilemap)
+				object.X += offset.X
+				object.Y += offset.Y
+				if object.GID != 0 {
+					object.GID = remap(object.GID)
+				}
+				layer.Objects = append(layer.Objects, object)
+			}
+
+		case Group:
+			if err := mergeLayers(layer.Layers, decoded[layerIndex].layers, chunkLayer.Layers, tilemap, entry, owned, remap); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// objectKey identifies copies of an object from different chunks, allowing for rounding of their position
+func objectKey(o Object) (string, error) {
+	o.X = math.Round(o.X*1e6) / 1e6
+	o.Y = math.Round(o.Y*1e6) / 1e6
+	b, err := json.Marshal(o)
+	return string(b), err
+}
+
+// withoutProperty returns the properties without the named property
+func withoutProperty(props Properties, name string) Properties {
+	var ret Properties
+	for _, p := range props {
+		if p.Name != name {
+			ret = append(ret, p)
+		}
+	}
+	return ret
+}
+
+// dedupeObjects removes the copies of objects that were in more than one chunk, like objects in the padding of chunks.
+// Pieces of clipped objects differ from each other and are all kept.
+func dedupeObjects(layers []Layer) error {
+	for layerIndex := range layers {
+		layer := &layers[layerIndex]
+
+		seen := map[string]bool{}
+		objects := layer.Objects[:0]
+		for _, object := range layer.Objects {
+			key, err := objectKey(object)
+			if err != nil {
+				return fmt.Errorf("failed to compare objects: %w", err)
+			}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			objects = append(objects, object)
+		}
+		layer.Objects = objects
+
+		// an object that is whole again doesn't need the origin id Split added to its copies
+		pieces := map[int]int{}
+		for _, object := range layer.Objects {
+			pieces[object.ID]++
+		}
+		for objectIndex := range layer.Objects {
+			object := &layer.Objects[objectIndex]
+			if pieces[object.ID] == 1 {
+				object.Properties = withoutProperty(object.Properties, OriginIDProperty)
+			}
+		}
+
+		if err := dedupeObjects(layer.Layers); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortObjects puts the objects back in the order of their ids in the source map, which merging chunk by chunk
+// mixes up. Pieces of clipped objects stay in the order of their chunks.
+func sortObjects(layers []Layer) {
+	for layerIndex := range layers {
+		objects := layers[layerIndex].Objects
+		sort.SliceStable(objects, func(i, j int) bool { return objects[i].ID < objects[j].ID })
+		sortObjects(layers[layerIndex].Layers)
+	}
+}
+
+// newMergedLayers prepares the decoded tile data and empty object lists of the merged layers
+func newMergedLayers(layers []Layer, size int) []decodedLayer {
+	decoded := make([]decodedLayer, len(layers))
+	for layerIndex := range layers {
+		layer := &layers[layerIndex]
+		switch layer.Type {
+		case TileLayer:
+			decoded[layerIndex].data = make([]GID, size)
+		case ObjectGroup:
+			layer.Objects = []Object{}
+		case Group:
+			decoded[layerIndex].layers = newMergedLayers(layer.Layers, size)
+		}
+	}
+	return decoded
+}
+
+// encodeMergedLayers encodes the merged tile data with the encoding and compression of the layers
+func encodeMergedLayers(layers []Layer, decoded []decodedLayer, width, height int) error {
+	for layerIndex := range layers {
+		layer := &layers[layerIndex]
+		switch layer.Type {
+		case TileLayer:
+			encoded, err := encodeLayerData(decoded[layerIndex].data, layer.Encoding, layer.Compression)
+			if err != nil {
+				return fmt.Errorf("failed to encode layer '%s': %w", layer.Name, err)
+			}
+			layer.Data = encoded
+			layer.WidthInTiles = width
+			layer.HeightInTiles = height
+
+		case Group:
+			if err := encodeMergedLayers(layer.Layers, decoded[layerIndex].layers, width, height); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Merge rebuilds a map from its chunks, placed by the tilemap entries of the master file.
+// The chunks are in the order of the entries, the chunks of empty entries are ignored.
+// The merged map covers the source bounds of the master file, or the tilemap entries when it has none.
+// Tiles in the padding of a chunk are taken from the chunk that owns them, objects in more than one chunk are
+// merged when the copies are the same and put back in the order of their ids, and the tilesets of all chunks
+// are merged with their gids renumbered.
+func Merge(master MasterFile, chunks []Tilemap) (Tilemap, error) {
+	if len(chunks) != len(master.Tilemaps) {
+		return Tilemap{}, fmt.Errorf("got %d chunks for %d tilemap entries", len(chunks), len(master.Tilemaps))
+	}
+
+	first := -1
+	bounds := tileBounds{empty: true}
+	for i, entry := range master.Tilemaps {
+		bounds.add(Chunk{X: entry.TileX, Y: entry.TileY, WidthInTiles: entry.WidthInTiles, HeightInTiles: entry.HeightInTiles})
+		if first < 0 && !entry.Empty {
+			first = i
+		}
+	}
+
+	if first < 0 {
+		return Tilemap{}, fmt.Errorf("no chunks to merge")
+	}
+
+	// the tilemaps leave out the empty edges of the source map when empty chunks are skipped
+	if source := master.Source; source != nil {
+		if bounds.minX < source.TileX || bounds.minY < source.TileY ||
+			bounds.maxX > source.TileX+source.WidthInTiles || bounds.maxY > source.TileY+source.HeightInTiles {
+			return Tilemap{}, fmt.Errorf("tilemaps reach beyond the source map")
+		}
+		bounds = tileBounds{minX: source.TileX, minY: source.TileY, maxX: source.TileX + source.WidthInTiles, maxY: source.TileY + source.HeightInTiles}
+	}
+
+	tilemap := chunks[first].Clone()
+	tilemap.Infinite = false
+	tilemap.WidthInTiles = bounds.maxX - bounds.minX
+	tilemap.HeightInTiles = bounds.maxY - bounds.minY
+	tilemap.OriginX = bounds.minX
+	tilemap.OriginY = bounds.minY
+	tilemap.Padding = Padding{}
+	tilemap.Empty = false
+	tilemap.Hash = ""
+	tilemap.Source = SourceMap{}
+	tilemap.Layers = layerMetadata(tilemap.Layers)
+	shiftStagger(&tilemap, master.Tilemaps[first].TileX-bounds.minX, master.Tilemaps[first].TileY-bounds.minY)
+
+	// padding properties are added by Split
+	for _, p := range (Padding{}).Properties() {
+		tilemap.Properties = withoutProperty(tilemap.Properties, p.Name)
+	}
+
+	var indices map[string]int
+	tilemap.Tilesets, indices = mergeTilesets(chunks, master)
+
+	decoded := newMergedLayers(tilemap.Layers, tilemap.WidthInTiles*tilemap.HeightInTiles)
+	for i, entry := range master.Tilemaps {
+		if entry.Empty {
+			continue
+		}
+
+		var padding Padding
+		if entry.Padding != nil {
+			padding = *entry.Padding
+		}
+
+		owned := chunkRegion{
+			left:   entry.TileX + padding.Left,
+			top:    entry.TileY + padding.Top,
+			width:  entry.WidthInTiles - padding.Left - padding.Right,
+			height: entry.HeightInTiles - padding.Top - padding.Bottom,
+		}
+
+		chunk := chunks[i]
+		if err := mergeLayers(tilemap.Layers, decoded, chunk.Layers, tilemap, entry, owned, chunkRemap(chunk, tilemap.Tilesets, indices)); err != nil {
+			return Tilemap{}, fmt.Errorf("failed to merge chunk '%s': %w", entry.Key, err)
+		}
+
+		if chunk.NextLayerID > tilemap.NextLayerID {
+			tilemap.NextLayerID = chunk.NextLayerID
+		}
+		if chunk.NextObjectID > tilemap.NextObjectID {
+			tilemap.NextObjectID = chunk.NextObjectID
+		}
+	}
+
+	// the ids chunks gave pieces of clipped objects are given again after merging, from the source next id
+	if master.Source != nil && master.Source.NextObjectID > 0 {
+		tilemap.NextObjectID = master.Source.NextObjectID
+	}
+
+	if err := dedupeObjects(tilemap.Layers); err != nil {
+		return Tilemap{}, err
+	}
+	sortObjects(tilemap.Layers)
+
+	// the source ids of renumbered chunks are restored, which can make NextObjectID of the chunks too small,
+	// and pieces of clipped objects get ids of their own
+	numberObjects(&tilemap, KeepObjectIDs)
+
+	if err := encodeMergedLayers(tilemap.Layers, decoded, tilemap.WidthInTiles, tilemap.HeightInTiles); err != nil {
+		return Tilemap{}, err
+	}
+
+	return tilemap, nil
+}
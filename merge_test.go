@@ -0,0 +1,126 @@
+package tmsplit
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeSparseTilesets(t *testing.T) {
+	chunks, err := Split(sparseTilemap(), SplitOptions{ChunkWidth: 1, ChunkHeight: 1, PruneTilesets: true})
+	if err != nil {
+		t.Fatalf("failed to split: %v", err)
+	}
+	master, err := CreateMasterFile(chunks, "sparse.json")
+	if err != nil {
+		t.Fatalf("failed to create master file: %v", err)
+	}
+
+	merged, err := Merge(master, chunks)
+	if err != nil {
+		t.Fatalf("failed to merge: %v", err)
+	}
+
+	var firstgids []int
+	for _, ts := range merged.Tilesets {
+		firstgids = append(firstgids, ts.FirstGID)
+	}
+	if !reflect.DeepEqual(firstgids, []int{1, 7}) {
+		t.Errorf("got first gids %v, want [1 7]", firstgids)
+	}
+
+	grid, err := merged.Layers[0].Tiles()
+	if err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	if want := []GID{6, 7, 0}; !reflect.DeepEqual(grid.GIDs, want) {
+		t.Errorf("got gids %v, want %v", grid.GIDs, want)
+	}
+}
+
+// reencodeLayers encodes the tile data of the layers again, so data compressed by other tools compares equal
+func reencodeLayers(t *testing.T, layers []Layer) {
+	t.Helper()
+
+	for layerIndex := range layers {
+		layer := &layers[layerIndex]
+		if layer.Type == TileLayer {
+			data, err := decodeLayerData(*layer)
+			if err != nil {
+				t.Fatalf("failed to decode layer '%s': %v", layer.Name, err)
+			}
+			if layer.Data, err = encodeLayerData(data, layer.Encoding, layer.Compression); err != nil {
+				t.Fatalf("failed to encode layer '%s': %v", layer.Name, err)
+			}
+		}
+		reencodeLayers(t, layer.Layers)
+	}
+}
+
+func splitMerge(t *testing.T, source Tilemap, opts SplitOptions, name string) Tilemap {
+	t.Helper()
+
+	chunks, err := Split(source, opts)
+	if err != nil {
+		t.Fatalf("failed to split: %v", err)
+	}
+	master, err := CreateMasterFile(chunks, name)
+	if err != nil {
+		t.Fatalf("failed to create master file: %v", err)
+	}
+
+	merged, err := Merge(master, chunks)
+	if err != nil {
+		t.Fatalf("failed to merge: %v", err)
+	}
+	return merged
+}
+
+func TestSplitMergeRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		opts SplitOptions
+		// like is the split to compare with when clipping leaves pieces of objects, instead of the source map
+		like *SplitOptions
+	}{
+		{"finite", "finite.tmx", SplitOptions{ChunkWidth: 2, ChunkHeight: 2}, nil},
+		{"finite json", "finite.json", SplitOptions{ChunkWidth: 3, ChunkHeight: 2}, nil},
+		{"renumbered duplicates", "finite.tmx", SplitOptions{ChunkWidth: 2, ChunkHeight: 2, Objects: DuplicateObjects, ObjectIDs: RenumberObjectIDs}, nil},
+		{"pruned tilesets", "finite.tmx", SplitOptions{ChunkWidth: 1, ChunkHeight: 1, PruneTilesets: true}, nil},
+		{"infinite", "infinite.tmx", SplitOptions{ChunkWidth: 2, ChunkHeight: 2}, nil},
+		{"infinite unaligned", "infinite.json", SplitOptions{ChunkWidth: 4, ChunkHeight: 3, Padding: 1}, nil},
+		{"padded", "finite.tmx", SplitOptions{ChunkWidth: 2, ChunkHeight: 2, Padding: 1}, nil},
+		{"padded duplicates", "finite.tmx", SplitOptions{ChunkWidth: 2, ChunkHeight: 2, Padding: 1, Objects: DuplicateObjects}, nil},
+		{"padded renumbered duplicates", "finite.json", SplitOptions{ChunkWidth: 1, ChunkHeight: 1, Padding: 2, Objects: DuplicateObjects, ObjectIDs: RenumberObjectIDs}, nil},
+		{"padded clipped", "finite.tmx", SplitOptions{ChunkWidth: 2, ChunkHeight: 2, Padding: 1, Objects: ClipObjects},
+			&SplitOptions{ChunkWidth: 2, ChunkHeight: 2, Objects: ClipObjects}},
+		{"padded clipped infinite", "infinite.tmx", SplitOptions{ChunkWidth: 2, ChunkHeight: 2, Padding: 2, Objects: ClipObjects},
+			&SplitOptions{ChunkWidth: 2, ChunkHeight: 2, Objects: ClipObjects}},
+		{"object order", "edges.tmx", SplitOptions{ChunkWidth: 2, ChunkHeight: 2}, nil},
+		{"skip empty", "edges.tmx", SplitOptions{ChunkWidth: 2, ChunkHeight: 2, EmptyChunks: SkipEmptyChunks}, nil},
+		{"mark empty", "edges.tmx", SplitOptions{ChunkWidth: 2, ChunkHeight: 2, EmptyChunks: MarkEmptyChunks, Padding: 1}, nil},
+		{"deduplicated", "edges.tmx", SplitOptions{ChunkWidth: 1, ChunkHeight: 1, Deduplicate: true}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := parseFixture(t, "testdata/"+tt.file)
+			want, err := NormalizeInfinite(source)
+			if err != nil {
+				t.Fatalf("failed to normalize: %v", err)
+			}
+			if tt.like != nil {
+				want = splitMerge(t, source, *tt.like, tt.file)
+			}
+
+			got := splitMerge(t, source, tt.opts, tt.file)
+
+			reencodeLayers(t, want.Layers)
+			reencodeLayers(t, got.Layers)
+
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("merged map differs:\ngot  %+v\nwant %+v", got, want)
+			}
+		})
+	}
+}
@@ -6,6 +6,7 @@ const (
 	NoCompression Compression = ""
 	Zlib          Compression = "zlib"
 	Gzip          Compression = "gzip"
+	Zstd          Compression = "zstd"
 )
 
 type DrawOrder string
@@ -36,9 +37,72 @@ type Point struct {
 	Y float64 `json:"y"`
 }
 
+// GID is a global tile id, the tile id with the flip flags Tiled stores in the high bits
+type GID uint32
+
+const (
+	FlippedHorizontallyFlag GID = 0x80000000
+	FlippedVerticallyFlag   GID = 0x40000000
+	FlippedDiagonallyFlag   GID = 0x20000000
+	RotatedHexagonal120Flag GID = 0x10000000
+
+	// GIDFlags masks all flags of a GID
+	GIDFlags = FlippedHorizontallyFlag | FlippedVerticallyFlag | FlippedDiagonallyFlag | RotatedHexagonal120Flag
+)
+
+// NewGID returns the GID of the global tile id with the given flip flags
+func NewGID(tileID uint32, flippedH, flippedV, flippedD bool) GID {
+	gid := GID(tileID) &^ GIDFlags
+	if flippedH {
+		gid |= FlippedHorizontallyFlag
+	}
+	if flippedV {
+		gid |= FlippedVerticallyFlag
+	}
+	if flippedD {
+		gid |= FlippedDiagonallyFlag
+	}
+	return gid
+}
+
+// TileID returns the global tile id without the flags, zero means no tile
+func (g GID) TileID() uint32 {
+	return uint32(g &^ GIDFlags)
+}
+
+// Flags returns only the flags of the GID
+func (g GID) Flags() GID {
+	return g & GIDFlags
+}
+
+// WithTileID returns the GID of another global tile id with the same flags
+func (g GID) WithTileID(tileID uint32) GID {
+	return GID(tileID)&^GIDFlags | g.Flags()
+}
+
+// FlippedH reports if the tile is flipped horizontally
+func (g GID) FlippedH() bool {
+	return g&FlippedHorizontallyFlag != 0
+}
+
+// FlippedV reports if the tile is flipped vertically
+func (g GID) FlippedV() bool {
+	return g&FlippedVerticallyFlag != 0
+}
+
+// FlippedD reports if the tile is flipped diagonally, which is how Tiled rotates tiles by 90 degrees
+func (g GID) FlippedD() bool {
+	return g&FlippedDiagonallyFlag != 0
+}
+
+// RotatedHex120 reports if the tile of a hexagonal map is rotated by 120 degrees
+func (g GID) RotatedHex120() bool {
+	return g&RotatedHexagonal120Flag != 0
+}
+
 type Object struct {
 	Ellipse    bool       `json:"ellipse,omitempty"`
-	GID        int        `json:"gid"`
+	GID        GID        `json:"gid"`
 	Height     float64    `json:"height"`
 	ID         int        `json:"id" xml:"id,attr"`
 	Name       string     `json:"name,omitempty" xml:"name,attr"`
@@ -60,9 +124,12 @@ type Orientation string
 
 const (
 	Orthogonal Orientation = "orthogonal"
-	Isometrict Orientation = "isometric"
+	Isometric  Orientation = "isometric"
 	Staggered  Orientation = "staggered"
 	Hexagonal  Orientation = "hexagonal"
+
+	// Deprecated: Isometrict is a misspelling kept for compatibility, use Isometric
+	Isometrict = Isometric
 )
 
 type RenderOrder string
@@ -133,10 +200,28 @@ type XMLImage struct {
 	Height int    `xml:"height,attr"`
 }
 
+type XMLChunk struct {
+	Data   string `xml:",chardata"`
+	X      int    `xml:"x,attr"`
+	Y      int    `xml:"y,attr"`
+	Width  int    `xml:"width,attr"`
+	Height int    `xml:"height,attr"`
+}
+
 type XMLData struct {
-	Data        string `xml:",innerxml"`
-	Encoding    string `xml:"encoding,attr"`
-	Compression string `xml:"compression,attr"`
+	Data        string     `xml:",innerxml"`
+	Encoding    string     `xml:"encoding,attr"`
+	Compression string     `xml:"compression,attr"`
+	Chunks      []XMLChunk `xml:"chunk"`
+}
+
+// Chunk is a piece of tile layer data of an infinite map
+type Chunk struct {
+	Data          string `json:"data"`
+	HeightInTiles int    `json:"height"`
+	WidthInTiles  int    `json:"width"`
+	X             int    `json:"x"`
+	Y             int    `json:"y"`
 }
 
 type XMLObjectGroup struct {
@@ -154,6 +239,7 @@ type XMLObjectGroup struct {
 }
 
 type Layer struct {
+	Chunks           []Chunk     `json:"chunks,omitempty"`
 	Compression      Compression `json:"compression"`
 	XMLData          XMLData     `json:"-" xml:"data"`
 	Data             string      `json:"data,omitempty"`
@@ -162,18 +248,18 @@ type Layer struct {
 	HeightInTiles    int         `json:"height,omitempty" xml:"height,attr"`
 	ID               int         `json:"id,omitempty" xml:"id,attr"`
 	Image            string      `json:"image,omitempty"`
-	Layers           []Layer     `json:"layers,omitempty"`
+	Layers           []Layer     `json:"layers,omitempty" xml:"-"`
 	Name             string      `json:"name,omitempty" xml:"name,attr"`
 	Objects          []Object    `json:"objects" xml:"object"`
 	OffsetX          float64     `json:"offsetx,omitempty" xml:"offsetx,attr"`
 	OffsetY          float64     `json:"offsety,omitempty" xml:"offsety,attr"`
-	Opacity          float64     `json:"opacity,omitempty" xml:"opacity,attr"`
+	Opacity          float64     `json:"opacity" xml:"opacity,attr"`
 	Properties       Properties  `json:"properties,omitempty" xml:"properties>property"`
 	StartX           int         `json:"startx,omitempty"`
 	StartY           int         `json:"starty,omitempty"`
-	TransparentColor string      `json:"transparentcolor,omitempty"`
+	TransparentColor string      `json:"transparentcolor,omitempty" xml:"trans,attr"`
 	Type             LayerType   `json:"type,omitempty"`
-	Visible          bool        `json:"visible,omitempty" xml:"visible,attr"`
+	Visible          bool        `json:"visible" xml:"visible,attr"`
 	WidthInTiles     int         `json:"width,omitempty" xml:"width,attr"`
 	X                int         `json:"x" xml:"x,attr"`
 	Y                int         `json:"y" xml:"y,attr"`
@@ -184,6 +270,7 @@ type TileTerrain []int
 type Tile struct {
 	Animation   []Frame     `json:"animation,omitempty" xml:"animation>frame"`
 	ID          int         `json:"id" xml:"id,attr"`
+	ImageXML    XMLImage    `json:"-" xml:"image"`
 	Image       string      `json:"image,omitempty"`
 	ImageHeight int         `json:"imageheight,omitempty"`
 	ImageWidth  int         `json:"imagewidth,omitempty"`
@@ -203,7 +290,7 @@ type Tileset struct {
 	Image            string      `json:"image,omitempty"`
 	ImageHeight      int         `json:"imageheight,omitempty"`
 	ImageWidth       int         `json:"imagewidth,omitempty"`
-	Margin           int         `json:"margin,omitempty" xml:"margin"`
+	Margin           int         `json:"margin,omitempty" xml:"margin,attr"`
 	Name             string      `json:"name,omitempty" xml:"name,attr"`
 	Properties       Properties  `json:"properties,omitempty" xml:"properties>property"`
 	Source           string      `json:"source,omitempty" xml:"source,attr"`
@@ -222,23 +309,65 @@ type Tileset struct {
 }
 
 type Tilemap struct {
-	HeightInTiles  int         `json:"height,omitempty" xml:"height,attr"`
-	WidthInTiles   int         `json:"width,omitempty" xml:"width,attr"`
-	TileHeight     int         `json:"tileheight,omitempty" xml:"tileheight,attr"`
-	TileWidth      int         `json:"tilewidth,omitempty" xml:"tilewidth,attr"`
-	Layers         []Layer     `json:"layers,omitempty" xml:"layer"`
-	XMLObjectGroup []Layer     `json:"-" xml:"objectgroup"`
-	Infinite       bool        `json:"infinite" xml:"infinite,attr"`
-	NextLayerID    int         `json:"nextlayerid,omitempty" xml:"nextlayerid,attr"`
-	NextObjectID   int         `json:"nextobjectid,omitempty" xml:"nextobjectid,attr"`
-	Orientation    Orientation `json:"orientation,omitempty" xml:"orientation,attr"`
-	Properties     Properties  `json:"properties,omitempty" xml:"properties>property"`
-	RenderOrder    RenderOrder `json:"renderorder,omitempty" xml:"renderorder,attr"`
-	StaggerAxis    string      `json:"staggeraxis,omitempty" xml:"staggeraxis,attr"`
-	StaggerIndex   string      `json:"staggerindex,omitempty" xml:"staggerindex,attr"`
-	TiledVersion   string      `json:"tiledversion,omitempty" xml:"tiledversion,attr"`
-	Tilesets       []Tileset   `json:"tilesets,omitempty" xml:"tileset"`
-	Version        float64     `json:"version,omitempty" xml:"version,attr"`
+	HeightInTiles int         `json:"height,omitempty" xml:"height,attr"`
+	WidthInTiles  int         `json:"width,omitempty" xml:"width,attr"`
+	TileHeight    int         `json:"tileheight,omitempty" xml:"tileheight,attr"`
+	TileWidth     int         `json:"tilewidth,omitempty" xml:"tilewidth,attr"`
+	Layers        []Layer     `json:"layers,omitempty" xml:"-"`
+	Infinite      bool        `json:"infinite" xml:"infinite,attr"`
+	NextLayerID   int         `json:"nextlayerid,omitempty" xml:"nextlayerid,attr"`
+	NextObjectID  int         `json:"nextobjectid,omitempty" xml:"nextobjectid,attr"`
+	Orientation   Orientation `json:"orientation,omitempty" xml:"orientation,attr"`
+	Properties    Properties  `json:"properties,omitempty" xml:"properties>property"`
+	RenderOrder   RenderOrder `json:"renderorder,omitempty" xml:"renderorder,attr"`
+	StaggerAxis   string      `json:"staggeraxis,omitempty" xml:"staggeraxis,attr"`
+	StaggerIndex  string      `json:"staggerindex,omitempty" xml:"staggerindex,attr"`
+	HexSideLength int         `json:"hexsidelength,omitempty" xml:"hexsidelength,attr"`
+	TiledVersion  string      `json:"tiledversion,omitempty" xml:"tiledversion,attr"`
+	Tilesets      []Tileset   `json:"tilesets,omitempty" xml:"tileset"`
+	Version       float64     `json:"version,omitempty" xml:"version,attr"`
+
+	// OriginX and OriginY is the tile position of the top left corner of the map within the source map,
+	// set on chunks created by Split and on normalised infinite maps
+	OriginX int `json:"-" xml:"-"`
+	OriginY int `json:"-" xml:"-"`
+	// Padding of chunks created by Split, the tiles around the edges borrowed from neighbouring chunks
+	Padding Padding `json:"-" xml:"-"`
+	// Empty is set on chunks without tiles or objects, when Split is asked to look for them
+	Empty bool `json:"-" xml:"-"`
+	// Hash of the content of chunks, when Split is asked to deduplicate them
+	Hash string `json:"-" xml:"-"`
+	// Source describes the map chunks created by Split were split from
+	Source SourceMap `json:"-" xml:"-"`
+}
+
+// SourceMap is what Merge needs to know about a split map beyond its chunks
+type SourceMap struct {
+	// TileX, TileY, WidthInTiles and HeightInTiles are the bounds of the map, in the coordinates of OriginX and OriginY
+	TileX         int `json:"tileX"`
+	TileY         int `json:"tileY"`
+	WidthInTiles  int `json:"widthInTiles"`
+	HeightInTiles int `json:"heightInTiles"`
+	// NextObjectID of the map, the chunks can have larger ones after giving pieces of clipped objects ids of their own
+	NextObjectID int `json:"nextObjectId,omitempty"`
+}
+
+// Padding is the number of tiles on each side of a chunk that belong to neighbouring chunks
+type Padding struct {
+	Left   int `json:"left"`
+	Top    int `json:"top"`
+	Right  int `json:"right"`
+	Bottom int `json:"bottom"`
+}
+
+// Properties returns the padding as int properties, as recorded in chunks
+func (p Padding) Properties() Properties {
+	return Properties{
+		{Name: "paddingLeft", Type: PropertyTypeInt, Value: int64(p.Left)},
+		{Name: "paddingTop", Type: PropertyTypeInt, Value: int64(p.Top)},
+		{Name: "paddingRight", Type: PropertyTypeInt, Value: int64(p.Right)},
+		{Name: "paddingBottom", Type: PropertyTypeInt, Value: int64(p.Bottom)},
+	}
 }
 
 func (props Properties) HasProperty(name, value string) bool {
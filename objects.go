@@ -0,0 +1,501 @@
+package tmsplit
+
+import "math"
+
+// OriginIDProperty is the int property with the id of the source object, set on objects crossing chunk boundaries
+// so the copies or pieces in different chunks can be matched
+const OriginIDProperty = "originId"
+
+// Rect is an axis aligned rectangle in pixels
+type Rect struct {
+	MinX, MinY, MaxX, MaxY float64
+}
+
+// overlaps1D reports if the closed range a overlaps the half open range b, a range of zero length overlaps if it's inside b
+func overlaps1D(amin, amax, bmin, bmax float64) bool {
+	if amin == amax {
+		return amin >= bmin && amin < bmax
+	}
+	return amin < bmax && amax > bmin
+}
+
+// overlaps reports if the rectangles overlap, r being half open
+func (r Rect) overlaps(o Rect) bool {
+	return overlaps1D(o.MinX, o.MaxX, r.MinX, r.MaxX) && overlaps1D(o.MinY, o.MaxY, r.MinY, r.MaxY)
+}
+
+func (r Rect) contains(o Rect) bool {
+	return o.MinX >= r.MinX && o.MaxX <= r.MaxX && o.MinY >= r.MinY && o.MaxY <= r.MaxY
+}
+
+func pointsRect(points []Point) Rect {
+	r := Rect{MinX: math.Inf(1), MinY: math.Inf(1), MaxX: math.Inf(-1), MaxY: math.Inf(-1)}
+	for _, p := range points {
+		r.MinX = math.Min(r.MinX, p.X)
+		r.MinY = math.Min(r.MinY, p.Y)
+		r.MaxX = math.Max(r.MaxX, p.X)
+		r.MaxY = math.Max(r.MaxY, p.Y)
+	}
+	return r
+}
+
+// ellipseSegments is the number of points of the outline of an ellipse
+const ellipseSegments = 32
+
+// rotate rotates the point around the origin by the angle in degrees, clockwise like Tiled
+func rotate(p, origin Point, degrees float64) Point {
+	if degrees == 0 {
+		return p
+	}
+
+	sin, cos := math.Sincos(degrees * math.Pi / 180)
+	dx, dy := p.X-origin.X, p.Y-origin.Y
+	return Point{origin.X + dx*cos - dy*sin, origin.Y + dx*sin + dy*cos}
+}
+
+// isRect reports if the object is a plain rectangle
+func (o Object) isRect() bool {
+	return o.GID == 0 && !o.Ellipse && !o.Point && o.Text == "" && o.Polygon == nil && o.Polyline == nil && o.Width > 0 && o.Height > 0
+}
+
+// WorldPolygon returns the outline of the object in map pixels with its rotation applied. Tile objects are anchored
+// at their bottom left corner, ellipses are approximated and points, like objects without a size, have a single point.
+// Polylines are open, the other outlines are closed. The tile objects of isometric maps are sized in rendered pixels
+// instead and Split places them by their position only.
+func (o Object) WorldPolygon() []Point {
+	origin := Point{o.X, o.Y}
+
+	var points []Point
+	switch {
+	case o.Polygon != nil:
+		points = offsetPoints(o.Polygon, o.X, o.Y)
+
+	case o.Polyline != nil:
+		points = offsetPoints(o.Polyline, o.X, o.Y)
+
+	case o.Point || o.Width == 0 && o.Height == 0:
+		return []Point{origin}
+
+	case o.Ellipse:
+		a, b := o.Width/2, o.Height/2
+		for i := 0; i < ellipseSegments; i++ {
+			sin, cos := math.Sincos(2 * math.Pi * float64(i) / ellipseSegments)
+			points = append(points, Point{o.X + a + a*cos, o.Y + b + b*sin})
+		}
+
+	case o.GID != 0:
+		points = []Point{{o.X, o.Y - o.Height}, {o.X + o.Width, o.Y - o.Height}, {o.X + o.Width, o.Y}, {o.X, o.Y}}
+
+	default:
+		points = []Point{{o.X, o.Y}, {o.X + o.Width, o.Y}, {o.X + o.Width, o.Y + o.Height}, {o.X, o.Y + o.Height}}
+	}
+
+	for i, p := range points {
+		points[i] = rotate(p, origin, o.Rotation)
+	}
+	return points
+}
+
+// Bounds returns the axis aligned bounds of the object in map pixels, see WorldPolygon
+func (o Object) Bounds() Rect {
+	if o.Ellipse && !o.Point && o.Polygon == nil && o.Polyline == nil {
+		// exact bounds instead of the bounds of the approximated outline
+		a, b := o.Width/2, o.Height/2
+		c := rotate(Point{o.X + a, o.Y + b}, Point{o.X, o.Y}, o.Rotation)
+		sin, cos := math.Sincos(o.Rotation * math.Pi / 180)
+		ex := math.Sqrt(a*a*cos*cos + b*b*sin*sin)
+		ey := math.Sqrt(a*a*sin*sin + b*b*cos*cos)
+		return Rect{MinX: c.X - ex, MinY: c.Y - ey, MaxX: c.X + ex, MaxY: c.Y + ey}
+	}
+
+	return pointsRect(o.WorldPolygon())
+}
+
+func offsetPoints(points []Point, x, y float64) []Point {
+	ret := make([]Point, len(points))
+	for i, p := range points {
+		ret[i] = Point{p.X + x, p.Y + y}
+	}
+	return ret
+}
+
+// objectCentroid returns the centroid of the area of closed outlines and of the points of polylines
+func objectCentroid(o Object) Point {
+	points := o.WorldPolygon()
+
+	if o.Polyline == nil {
+		var area, cx, cy float64
+		for i, p := range points {
+			q := points[(i+1)%len(points)]
+			cross := p.X*q.Y - q.X*p.Y
+			area += cross
+			cx += (p.X + q.X) * cross
+			cy += (p.Y + q.Y) * cross
+		}
+		if area != 0 {
+			return Point{cx / (3 * area), cy / (3 * area)}
+		}
+	}
+
+	var c Point
+	for _, p := range points {
+		c.X += p.X / float64(len(points))
+		c.Y += p.Y / float64(len(points))
+	}
+	return c
+}
+
+// clipPolygon clips a closed outline to the rectangle, one edge at a time
+func clipPolygon(points []Point, r Rect) []Point {
+	edges := []struct {
+		inside    func(Point) bool
+		intersect func(a, b Point) Point
+	}{
+		{func(p Point) bool { return p.X >= r.MinX }, func(a, b Point) Point { return lerpX(a, b, r.MinX) }},
+		{func(p Point) bool { return p.X <= r.MaxX }, func(a, b Point) Point { return lerpX(a, b, r.MaxX) }},
+		{func(p Point) bool { return p.Y >= r.MinY }, func(a, b Point) Point { return lerpY(a, b, r.MinY) }},
+		{func(p Point) bool { return p.Y <= r.MaxY }, func(a, b Point) Point { return lerpY(a, b, r.MaxY) }},
+	}
+
+	for _, edge := range edges {
+		if len(points) == 0 {
+			break
+		}
+
+		var clipped []Point
+		// corners on the edge are their own intersection, they're added once
+		add := func(p Point) {
+			if len(clipped) == 0 || clipped[len(clipped)-1] != p {
+				clipped = append(clipped, p)
+			}
+		}
+
+		prev := points[len(points)-1]
+		for _, p := range points {
+			switch {
+			case edge.inside(p) && !edge.inside(prev):
+				add(edge.intersect(prev, p))
+				add(p)
+			case edge.inside(p):
+				add(p)
+			case edge.inside(prev):
+				add(edge.intersect(prev, p))
+			}
+			prev = p
+		}
+		if len(clipped) > 1 && clipped[0] == clipped[len(clipped)-1] {
+			clipped = clipped[:len(clipped)-1]
+		}
+		points = clipped
+	}
+
+	return points
+}
+
+func lerpX(a, b Point, x float64) Point {
+	return Point{x, a.Y + (b.Y-a.Y)*(x-a.X)/(b.X-a.X)}
+}
+
+func lerpY(a, b Point, y float64) Point {
+	return Point{a.X + (b.X-a.X)*(y-a.Y)/(b.Y-a.Y), y}
+}
+
+// clipSegment clips the segment to the rectangle, reporting false if no part of it is inside
+func clipSegment(a, b Point, r Rect) (Point, Point, bool) {
+	t0, t1 := 0.0, 1.0
+	dx, dy := b.X-a.X, b.Y-a.Y
+
+	for _, edge := range [][2]float64{
+		{-dx, a.X - r.MinX},
+		{dx, r.MaxX - a.X},
+		{-dy, a.Y - r.MinY},
+		{dy, r.MaxY - a.Y},
+	} {
+		p, q := edge[0], edge[1]
+		if p == 0 {
+			if q < 0 {
+				return a, b, false
+			}
+			continue
+		}
+
+		t := q / p
+		if p < 0 {
+			t0 = math.Max(t0, t)
+		} else {
+			t1 = math.Min(t1, t)
+		}
+	}
+
+	if t0 > t1 {
+		return a, b, false
+	}
+
+	return Point{a.X + t0*dx, a.Y + t0*dy}, Point{a.X + t1*dx, a.Y + t1*dy}, true
+}
+
+// clipPolyline clips an open outline to the rectangle, which can split it in several pieces
+func clipPolyline(points []Point, r Rect) [][]Point {
+	var pieces [][]Point
+	var piece []Point
+	for i := 0; i+1 < len(points); i++ {
+		a, b, ok := clipSegment(points[i], points[i+1], r)
+		if !ok {
+			continue
+		}
+
+		if len(piece) > 0 && piece[len(piece)-1] == a {
+			piece = append(piece, b)
+			continue
+		}
+
+		if len(piece) > 1 {
+			pieces = append(pieces, piece)
+		}
+		piece = []Point{a, b}
+	}
+
+	if len(piece) > 1 {
+		pieces = append(pieces, piece)
+	}
+	return pieces
+}
+
+// withPoints returns a copy of the object at the first point with the points relative to it, the rotation is part of the points
+func withPoints(o Object, points []Point, polygon bool) Object {
+	o = o.Clone()
+	o.X, o.Y = points[0].X, points[0].Y
+	o.Rotation = 0
+	relative := offsetPoints(points, -o.X, -o.Y)
+	if polygon {
+		o.Polygon = relative
+	} else {
+		o.Polyline = relative
+	}
+	return o
+}
+
+// clippable reports if clipObject can clip the object
+func (o Object) clippable() bool {
+	return o.isRect() || o.Polygon != nil || o.Polyline != nil
+}
+
+// clipObject clips rectangles, polygons and polylines to the rectangle, rotated rectangles become polygons.
+// Other objects can't be clipped and are returned whole.
+func clipObject(o Object, r Rect) []Object {
+	switch {
+	case o.isRect() && o.Rotation == 0:
+		bounds := o.Bounds()
+		clipped := Rect{
+			MinX: math.Max(bounds.MinX, r.MinX),
+			MinY: math.Max(bounds.MinY, r.MinY),
+			MaxX: math.Min(bounds.MaxX, r.MaxX),
+			MaxY: math.Min(bounds.MaxY, r.MaxY),
+		}
+		if clipped.MinX >= clipped.MaxX || clipped.MinY >= clipped.MaxY {
+			return nil
+		}
+
+		o = o.Clone()
+		o.X, o.Y = clipped.MinX, clipped.MinY
+		o.Width, o.Height = clipped.MaxX-clipped.MinX, clipped.MaxY-clipped.MinY
+		return []Object{o}
+
+	case o.Polygon != nil || o.isRect():
+		points := clipPolygon(o.WorldPolygon(), r)
+		if len(points) < 3 {
+			return nil
+		}
+		o = withPoints(o, points, true)
+		o.Width, o.Height = 0, 0
+		return []Object{o}
+
+	case o.Polyline != nil:
+		var objects []Object
+		for _, piece := range clipPolyline(o.WorldPolygon(), r) {
+			objects = append(objects, withPoints(o, piece, false))
+		}
+		return objects
+	}
+
+	return []Object{o}
+}
+
+func (r chunkRegion) containsTile(x, y int) bool {
+	return x >= r.left && x < r.left+r.width && y >= r.top && y < r.top+r.height
+}
+
+// objectRect returns the region in object coordinates
+func (r chunkRegion) objectRect(tilemap Tilemap) Rect {
+	topLeft := tileToObject(r.left, r.top, tilemap)
+	bottomRight := tileToObject(r.left+r.width, r.top+r.height, tilemap)
+
+	// the chunks along the right and bottom edges include the part of the map sticking out on staggered maps
+	size := mapObjectSize(tilemap)
+	if r.left+r.width == tilemap.WidthInTiles {
+		bottomRight.X = math.Max(bottomRight.X, size.X)
+	}
+	if r.top+r.height == tilemap.HeightInTiles {
+		bottomRight.Y = math.Max(bottomRight.Y, size.Y)
+	}
+
+	return Rect{MinX: topLeft.X, MinY: topLeft.Y, MaxX: bottomRight.X, MaxY: bottomRight.Y}
+}
+
+// withOriginID returns the object with the origin id property set
+func withOriginID(o Object) Object {
+	for _, p := range o.Properties {
+		if p.Name == OriginIDProperty {
+			return o
+		}
+	}
+
+	o = o.Clone()
+	o.Properties = append(o.Properties, Property{Name: OriginIDProperty, Type: PropertyTypeInt, Value: int64(o.ID)})
+	return o
+}
+
+// anchorTile returns the tile of the position an object is assigned by, limited to the map so objects sticking
+// out of it are kept by the chunks along its edges
+func anchorTile(p Point, tilemap Tilemap) (int, int) {
+	x, y := objectToTile(p, tilemap)
+	if x >= tilemap.WidthInTiles {
+		x = tilemap.WidthInTiles - 1
+	}
+	if y >= tilemap.HeightInTiles {
+		y = tilemap.HeightInTiles - 1
+	}
+	if p.X < 0 || x < 0 {
+		x = 0
+	}
+	if p.Y < 0 || y < 0 {
+		y = 0
+	}
+	return x, y
+}
+
+// isometricTile reports if the object is a tile object of an isometric map. Tiled draws these around the bottom
+// centre of their position, with a size in rendered pixels, so they take up only their position in object coordinates.
+func isometricTile(o Object, tilemap Tilemap) bool {
+	return tilemap.Orientation == Isometric && o.GID != 0
+}
+
+// objectBounds returns the bounds of the object in object coordinates of the map
+func objectBounds(o Object, tilemap Tilemap) Rect {
+	if isometricTile(o, tilemap) {
+		return Rect{MinX: o.X, MinY: o.Y, MaxX: o.X, MaxY: o.Y}
+	}
+	return o.Bounds()
+}
+
+// assignObject returns the objects, in source map object coordinates, the source object adds to the chunk of the region
+func assignObject(o Object, tilemap Tilemap, grid chunkGrid, region chunkRegion, policy ObjectPolicy) []Object {
+	switch policy {
+	case ObjectsByCentroid:
+		centroid := Point{o.X, o.Y}
+		if !isometricTile(o, tilemap) {
+			centroid = objectCentroid(o)
+		}
+		if region.containsTile(anchorTile(centroid, tilemap)) {
+			return []Object{o}
+		}
+		return nil
+
+	case DuplicateObjects, ClipObjects:
+		r := region.objectRect(tilemap)
+		bounds := objectBounds(o, tilemap)
+		if !r.overlaps(bounds) {
+			return nil
+		}
+
+		// objects cross chunks by the grid of chunks without padding, so the copies in the padding of
+		// neighbouring chunks are the same as the copy in the chunk that owns them
+		if grid.cell(anchorTile(Point{bounds.MinX, bounds.MinY}, tilemap)).objectRect(tilemap).contains(bounds) {
+			return []Object{o}
+		}
+
+		if policy == DuplicateObjects || !o.clippable() {
+			return []Object{withOriginID(o)}
+		}
+
+		var objects []Object
+		for _, cell := range grid.cells(region) {
+			for _, piece := range clipObject(o, cell.objectRect(tilemap)) {
+				if r.overlaps(piece.Bounds()) {
+					objects = append(objects, withOriginID(piece))
+				}
+			}
+		}
+		return objects
+	}
+
+	bounds := objectBounds(o, tilemap)
+	if region.containsTile(anchorTile(Point{bounds.MinX, bounds.MinY}, tilemap)) {
+		return []Object{o}
+	}
+	return nil
+}
+
+// OriginID returns the id of the object in the source map, from the OriginIDProperty if it has one
+func (o Object) OriginID() int {
+	for _, p := range o.Properties {
+		if p.Name == OriginIDProperty {
+			if id, ok := p.Value.(int64); ok {
+				return int(id)
+			}
+		}
+	}
+	return o.ID
+}
+
+func maxIDs(layers []Layer, maxLayerID, maxObjectID *int) {
+	for _, layer := range layers {
+		if layer.ID > *maxLayerID {
+			*maxLayerID = layer.ID
+		}
+		for _, o := range layer.Objects {
+			if o.ID > *maxObjectID {
+				*maxObjectID = o.ID
+			}
+		}
+		maxIDs(layer.Layers, maxLayerID, maxObjectID)
+	}
+}
+
+func numberLayerObjects(layers []Layer, policy ObjectIDPolicy, seen map[int]bool, next *int) {
+	for layerIndex := range layers {
+		layer := &layers[layerIndex]
+		for objectIndex := range layer.Objects {
+			o := &layer.Objects[objectIndex]
+			if policy == RenumberObjectIDs || seen[o.ID] {
+				*o = withOriginID(*o)
+				o.ID = *next
+				*next++
+			}
+			seen[o.ID] = true
+		}
+		numberLayerObjects(layer.Layers, policy, seen, next)
+	}
+}
+
+// numberObjects applies the object id policy to the objects of the chunk and makes NextObjectID and NextLayerID
+// larger than the ids in the chunk
+func numberObjects(tm *Tilemap, policy ObjectIDPolicy) {
+	var maxLayerID, maxObjectID int
+	maxIDs(tm.Layers, &maxLayerID, &maxObjectID)
+
+	if tm.NextLayerID <= maxLayerID {
+		tm.NextLayerID = maxLayerID + 1
+	}
+
+	next := tm.NextObjectID
+	if next <= maxObjectID {
+		next = maxObjectID + 1
+	}
+	if policy == RenumberObjectIDs {
+		next = 1
+	}
+
+	numberLayerObjects(tm.Layers, policy, map[int]bool{}, &next)
+	tm.NextObjectID = next
+}
@@ -0,0 +1,322 @@
+package tmsplit
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+const epsilon = 1e-9
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < epsilon
+}
+
+func pointsEqual(a, b []Point) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !almostEqual(a[i].X, b[i].X) || !almostEqual(a[i].Y, b[i].Y) {
+			return false
+		}
+	}
+	return true
+}
+
+// objectsMap has a single object layer on two chunks of 32x32 pixels side by side
+func objectsMap(o Object) Tilemap {
+	o.ID = 1
+	o.Visible = true
+	return Tilemap{
+		WidthInTiles: 4, HeightInTiles: 2, TileWidth: 16, TileHeight: 16,
+		Orientation: Orthogonal, RenderOrder: "right-down", NextLayerID: 2, NextObjectID: 2,
+		Layers: []Layer{
+			{ID: 1, Name: "objects", Type: ObjectGroup, DrawOrder: DrawOrderIndex, Opacity: 1, Visible: true, Objects: []Object{o}},
+		},
+	}
+}
+
+// clipped is an object expected in a chunk, in chunk coordinates
+type clipped struct {
+	X, Y, Width, Height float64
+	Polygon, Polyline   []Point
+	// Split is set on pieces and on objects in more than one chunk, which have the origin id property
+	Split bool
+}
+
+func (c clipped) String() string {
+	return fmt.Sprintf("{%g,%g %gx%g polygon %v polyline %v split %t}", c.X, c.Y, c.Width, c.Height, c.Polygon, c.Polyline, c.Split)
+}
+
+func TestClipObjects(t *testing.T) {
+	tests := []struct {
+		name   string
+		object Object
+		chunks [2][]clipped
+	}{
+		{
+			name:   "rectangle inside",
+			object: Object{X: 4, Y: 4, Width: 8, Height: 8},
+			chunks: [2][]clipped{{{X: 4, Y: 4, Width: 8, Height: 8}}, nil},
+		},
+		{
+			name:   "rectangle across",
+			object: Object{X: 24, Y: 4, Width: 16, Height: 8},
+			chunks: [2][]clipped{
+				{{X: 24, Y: 4, Width: 8, Height: 8, Split: true}},
+				{{X: 0, Y: 4, Width: 8, Height: 8, Split: true}},
+			},
+		},
+		{
+			name:   "rectangle ending on the edge",
+			object: Object{X: 16, Y: 4, Width: 16, Height: 8},
+			chunks: [2][]clipped{{{X: 16, Y: 4, Width: 16, Height: 8}}, nil},
+		},
+		{
+			name:   "rectangle starting on the edge",
+			object: Object{X: 32, Y: 4, Width: 8, Height: 8},
+			chunks: [2][]clipped{nil, {{X: 0, Y: 4, Width: 8, Height: 8}}},
+		},
+		{
+			name:   "point on the edge",
+			object: Object{X: 32, Y: 4, Point: true},
+			chunks: [2][]clipped{nil, {{X: 0, Y: 4}}},
+		},
+		{
+			name:   "polygon across",
+			object: Object{X: 24, Y: 8, Polygon: []Point{{0, 0}, {16, 0}, {0, 16}}},
+			chunks: [2][]clipped{
+				{{X: 24, Y: 8, Polygon: []Point{{0, 0}, {8, 0}, {8, 8}, {0, 16}}, Split: true}},
+				{{X: 0, Y: 8, Polygon: []Point{{0, 0}, {8, 0}, {0, 8}}, Split: true}},
+			},
+		},
+		{
+			name:   "rotated rectangle across",
+			object: Object{X: 36, Y: 4, Width: 16, Height: 8, Rotation: 90},
+			chunks: [2][]clipped{
+				{{X: 32, Y: 4, Polygon: []Point{{0, 0}, {0, 16}, {-4, 16}, {-4, 0}}, Split: true}},
+				{{X: 0, Y: 4, Polygon: []Point{{0, 0}, {4, 0}, {4, 16}, {0, 16}}, Split: true}},
+			},
+		},
+		{
+			name:   "diamond with corners on the edge",
+			object: Object{X: 32, Y: 4, Width: 8, Height: 8, Rotation: 45},
+			chunks: [2][]clipped{
+				{{X: 32, Y: 4, Polygon: []Point{{0, 0}, {0, 8 * math.Sqrt2}, {-4 * math.Sqrt2, 4 * math.Sqrt2}}, Split: true}},
+				{{X: 0, Y: 4, Polygon: []Point{{0, 0}, {4 * math.Sqrt2, 4 * math.Sqrt2}, {0, 8 * math.Sqrt2}}, Split: true}},
+			},
+		},
+		{
+			name:   "rotated rectangle inside",
+			object: Object{X: 24, Y: 8, Width: 16, Height: 8, Rotation: 90},
+			chunks: [2][]clipped{{{X: 24, Y: 8, Width: 16, Height: 8}}, nil},
+		},
+		{
+			name:   "polyline leaving and entering again",
+			object: Object{X: 8, Y: 4, Polyline: []Point{{0, 0}, {32, 0}, {32, 16}, {0, 16}}},
+			chunks: [2][]clipped{
+				{
+					{X: 8, Y: 4, Polyline: []Point{{0, 0}, {24, 0}}, Split: true},
+					{X: 32, Y: 20, Polyline: []Point{{0, 0}, {-24, 0}}, Split: true},
+				},
+				{{X: 0, Y: 4, Polyline: []Point{{0, 0}, {8, 0}, {8, 16}, {0, 16}}, Split: true}},
+			},
+		},
+		{
+			name:   "polyline along the edge",
+			object: Object{X: 32, Y: 4, Polyline: []Point{{0, 0}, {0, 16}}},
+			chunks: [2][]clipped{nil, {{X: 0, Y: 4, Polyline: []Point{{0, 0}, {0, 16}}}}},
+		},
+		{
+			name:   "ellipse across",
+			object: Object{X: 24, Y: 4, Width: 16, Height: 8, Ellipse: true},
+			chunks: [2][]clipped{
+				{{X: 24, Y: 4, Width: 16, Height: 8, Split: true}},
+				{{X: -8, Y: 4, Width: 16, Height: 8, Split: true}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks, err := Split(objectsMap(tt.object), SplitOptions{ChunkWidth: 2, ChunkHeight: 2, Objects: ClipObjects})
+			if err != nil {
+				t.Fatalf("failed to split: %v", err)
+			}
+			if len(chunks) != 2 {
+				t.Fatalf("got %d chunks, want 2", len(chunks))
+			}
+
+			for chunkIndex, chunk := range chunks {
+				var got []clipped
+				for _, o := range chunk.Layers[0].Objects {
+					if o.OriginID() != 1 {
+						t.Errorf("chunk %d: got origin id %d, want 1", chunkIndex, o.OriginID())
+					}
+					got = append(got, clipped{
+						X: o.X, Y: o.Y, Width: o.Width, Height: o.Height, Polygon: o.Polygon, Polyline: o.Polyline,
+						Split: len(o.Properties) > 0,
+					})
+				}
+
+				want := tt.chunks[chunkIndex]
+				if len(got) != len(want) {
+					t.Errorf("chunk %d: got %v, want %v", chunkIndex, got, want)
+					continue
+				}
+				for i := range got {
+					g, w := got[i], want[i]
+					if !almostEqual(g.X, w.X) || !almostEqual(g.Y, w.Y) || !almostEqual(g.Width, w.Width) || !almostEqual(g.Height, w.Height) ||
+						!pointsEqual(g.Polygon, w.Polygon) || !pointsEqual(g.Polyline, w.Polyline) || g.Split != w.Split {
+						t.Errorf("chunk %d, object %d: got %v, want %v", chunkIndex, i, g, w)
+					}
+				}
+			}
+		})
+	}
+}
+
+func rectEqual(a, b Rect) bool {
+	return almostEqual(a.MinX, b.MinX) && almostEqual(a.MinY, b.MinY) && almostEqual(a.MaxX, b.MaxX) && almostEqual(a.MaxY, b.MaxY)
+}
+
+func TestObjectBounds(t *testing.T) {
+	h := math.Sqrt2 / 2
+
+	// an ellipse of 20x10 at 0,0 rotated by 45 degrees, its center rotated around 0,0
+	cx, cy := 10*h-5*h, 10*h+5*h
+	e := math.Sqrt(62.5)
+
+	tests := []struct {
+		name    string
+		object  Object
+		polygon []Point // nil for ellipses, whose outline is approximated
+		bounds  Rect
+	}{
+		{
+			name:    "rectangle",
+			object:  Object{X: 10, Y: 20, Width: 30, Height: 10},
+			polygon: []Point{{10, 20}, {40, 20}, {40, 30}, {10, 30}},
+			bounds:  Rect{MinX: 10, MinY: 20, MaxX: 40, MaxY: 30},
+		},
+		{
+			name:    "rectangle at 90 degrees",
+			object:  Object{X: 10, Y: 20, Width: 30, Height: 10, Rotation: 90},
+			polygon: []Point{{10, 20}, {10, 50}, {0, 50}, {0, 20}},
+			bounds:  Rect{MinX: 0, MinY: 20, MaxX: 10, MaxY: 50},
+		},
+		{
+			name:    "square at 45 degrees",
+			object:  Object{Width: 10, Height: 10, Rotation: 45},
+			polygon: []Point{{0, 0}, {10 * h, 10 * h}, {0, 20 * h}, {-10 * h, 10 * h}},
+			bounds:  Rect{MinX: -10 * h, MinY: 0, MaxX: 10 * h, MaxY: 20 * h},
+		},
+		{
+			name:    "point",
+			object:  Object{X: 5, Y: 6, Point: true},
+			polygon: []Point{{5, 6}},
+			bounds:  Rect{MinX: 5, MinY: 6, MaxX: 5, MaxY: 6},
+		},
+		{
+			name:    "polygon at 90 degrees",
+			object:  Object{X: 4, Y: 2, Polygon: []Point{{0, 0}, {10, 0}, {0, 5}}, Rotation: 90},
+			polygon: []Point{{4, 2}, {4, 12}, {-1, 2}},
+			bounds:  Rect{MinX: -1, MinY: 2, MaxX: 4, MaxY: 12},
+		},
+		{
+			name:    "polyline",
+			object:  Object{X: 4, Y: 2, Polyline: []Point{{0, 0}, {10, -2}, {3, 8}}},
+			polygon: []Point{{4, 2}, {14, 0}, {7, 10}},
+			bounds:  Rect{MinX: 4, MinY: 0, MaxX: 14, MaxY: 10},
+		},
+		{
+			name:    "tile object anchored bottom left",
+			object:  Object{X: 10, Y: 40, Width: 16, Height: 16, GID: 1},
+			polygon: []Point{{10, 24}, {26, 24}, {26, 40}, {10, 40}},
+			bounds:  Rect{MinX: 10, MinY: 24, MaxX: 26, MaxY: 40},
+		},
+		{
+			name:    "tile object at 90 degrees",
+			object:  Object{X: 10, Y: 40, Width: 16, Height: 16, GID: 1, Rotation: 90},
+			polygon: []Point{{26, 40}, {26, 56}, {10, 56}, {10, 40}},
+			bounds:  Rect{MinX: 10, MinY: 40, MaxX: 26, MaxY: 56},
+		},
+		{
+			name:   "ellipse",
+			object: Object{Width: 20, Height: 10, Ellipse: true},
+			bounds: Rect{MinX: 0, MinY: 0, MaxX: 20, MaxY: 10},
+		},
+		{
+			name:   "ellipse at 90 degrees",
+			object: Object{Width: 20, Height: 10, Ellipse: true, Rotation: 90},
+			bounds: Rect{MinX: -10, MinY: 0, MaxX: 0, MaxY: 20},
+		},
+		{
+			name:   "ellipse at 45 degrees",
+			object: Object{Width: 20, Height: 10, Ellipse: true, Rotation: 45},
+			bounds: Rect{MinX: cx - e, MinY: cy - e, MaxX: cx + e, MaxY: cy + e},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			polygon := tt.object.WorldPolygon()
+			if tt.polygon != nil && !pointsEqual(polygon, tt.polygon) {
+				t.Errorf("got polygon %v, want %v", polygon, tt.polygon)
+			}
+
+			bounds := tt.object.Bounds()
+			if !rectEqual(bounds, tt.bounds) {
+				t.Errorf("got bounds %+v, want %+v", bounds, tt.bounds)
+			}
+
+			// the approximated outline of ellipses stays inside the exact bounds
+			if tt.object.Ellipse {
+				if len(polygon) != ellipseSegments {
+					t.Errorf("got %d points, want %d", len(polygon), ellipseSegments)
+				}
+				for _, p := range polygon {
+					if p.X < bounds.MinX-epsilon || p.X > bounds.MaxX+epsilon || p.Y < bounds.MinY-epsilon || p.Y > bounds.MaxY+epsilon {
+						t.Errorf("point %v outside bounds %+v", p, bounds)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestAssignObjectsOutsideMap(t *testing.T) {
+	tests := []struct {
+		name   string
+		object Object
+		policy ObjectPolicy
+		chunk  int
+	}{
+		{"tile object on the top edge", Object{X: 4, Y: 8, Width: 16, Height: 32, GID: 1}, ObjectsByOrigin, 0},
+		{"tile object on the top edge by centroid", Object{X: 40, Y: 8, Width: 16, Height: 32, GID: 1}, ObjectsByCentroid, 1},
+		{"rectangle left of the map", Object{X: -40, Y: 4, Width: 8, Height: 8}, ObjectsByOrigin, 0},
+		{"polygon reaching above the map", Object{X: 40, Y: 4, Polygon: []Point{{0, 0}, {8, -30}, {16, 0}}}, ObjectsByOrigin, 1},
+		{"point right of the map", Object{X: 100, Y: 4, Point: true}, ObjectsByOrigin, 1},
+		{"rectangle below the map", Object{X: 36, Y: 60, Width: 8, Height: 8}, ObjectsByCentroid, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks, err := Split(objectsMap(tt.object), SplitOptions{ChunkWidth: 2, ChunkHeight: 2, Objects: tt.policy})
+			if err != nil {
+				t.Fatalf("failed to split: %v", err)
+			}
+
+			for chunkIndex, chunk := range chunks {
+				want := 0
+				if chunkIndex == tt.chunk {
+					want = 1
+				}
+				if got := len(chunk.Layers[0].Objects); got != want {
+					t.Errorf("chunk %d: got %d objects, want %d", chunkIndex, got, want)
+				}
+			}
+		})
+	}
+}
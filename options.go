@@ -0,0 +1,186 @@
+package tmsplit
+
+import (
+	"errors"
+	"fmt"
+)
+
+// EmptyChunkPolicy decides what Split does with chunks without tiles or objects
+type EmptyChunkPolicy string
+
+const (
+	// KeepEmptyChunks treats empty chunks like any other chunk
+	KeepEmptyChunks EmptyChunkPolicy = ""
+	// SkipEmptyChunks leaves empty chunks out
+	SkipEmptyChunks EmptyChunkPolicy = "skip"
+	// MarkEmptyChunks keeps empty chunks with Empty set, so they're flagged in the master file instead of saved
+	MarkEmptyChunks EmptyChunkPolicy = "mark"
+)
+
+// ObjectPolicy decides which chunks an object is added to
+type ObjectPolicy string
+
+const (
+	// ObjectsByOrigin adds an object to the chunk containing the top left corner of its bounds, see Object.Bounds.
+	// Objects outside the map are added to the nearest chunk along its edges.
+	ObjectsByOrigin ObjectPolicy = ""
+	// ObjectsByCentroid adds an object to the chunk containing its centroid, or the nearest chunk along the edges
+	// of the map when the centroid is outside it
+	ObjectsByCentroid ObjectPolicy = "centroid"
+	// DuplicateObjects adds an object to every chunk it overlaps
+	DuplicateObjects ObjectPolicy = "duplicate"
+	// ClipObjects adds the part of an object inside each chunk it overlaps. Rectangles, polygons and polylines are
+	// clipped to the chunks without their padding, padding gets the parts of the neighbouring chunks, and other
+	// objects are duplicated.
+	ClipObjects ObjectPolicy = "clip"
+)
+
+// ObjectIDPolicy decides the ids of the objects in chunks
+type ObjectIDPolicy string
+
+const (
+	// KeepObjectIDs keeps the ids of the source map, only extra pieces of an object in the same chunk get new ids
+	KeepObjectIDs ObjectIDPolicy = ""
+	// RenumberObjectIDs numbers the objects of each chunk from 1, with the source id in the OriginIDProperty
+	RenumberObjectIDs ObjectIDPolicy = "renumber"
+)
+
+// SplitOptions controls how Split produces chunks
+type SplitOptions struct {
+	// ChunkWidth and ChunkHeight is the size of each chunk in tiles, chunks along the right and bottom
+	// edges are smaller when the map size isn't a multiple of the chunk size
+	ChunkWidth  int
+	ChunkHeight int
+
+	// Padding is the number of tiles from neighbouring chunks included on each side of a chunk.
+	// The padding of each chunk is recorded in its properties and in the master file.
+	Padding int
+
+	// EmptyChunks decides what to do with chunks where all tile layers are empty and there are no objects
+	EmptyChunks EmptyChunkPolicy
+
+	// Objects decides which chunks get an object, objects added to more than one chunk get the OriginIDProperty
+	Objects ObjectPolicy
+	// ObjectIDs decides the ids of the objects in chunks, NextObjectID and NextLayerID are valid for each chunk either way
+	ObjectIDs ObjectIDPolicy
+
+	// Deduplicate hashes the content of each chunk so chunks with the same content can share a file
+	Deduplicate bool
+
+	// PruneTilesets leaves out the tilesets a chunk doesn't use and renumbers the gids of the chunk to match
+	PruneTilesets bool
+
+	// Compression of the chunk tile layer data, nil keeps the compression of the source layer
+	Compression *Compression
+	// Encoding of the chunk tile layer data, nil keeps the encoding of the source layer
+	Encoding *Encoding
+
+	// IncludeLayers lists the names of the layers to keep, all layers are kept if empty.
+	// Groups are kept with all their children when named, or with the named children otherwise.
+	IncludeLayers []string
+	// ExcludeLayers lists the names of the layers to leave out, a group is left out with all its children
+	ExcludeLayers []string
+	// ExcludeHidden leaves out the layers that aren't visible
+	ExcludeHidden bool
+
+	// Workers is the number of chunks built concurrently, zero or less uses the number of CPUs
+	Workers int
+}
+
+// DefaultSplitOptions returns the options the CLI uses by default
+func DefaultSplitOptions() SplitOptions {
+	return SplitOptions{
+		ChunkWidth:  100,
+		ChunkHeight: 100,
+	}
+}
+
+// Validate checks that the options are usable
+func (opts SplitOptions) Validate() error {
+	if opts.ChunkWidth <= 0 {
+		return fmt.Errorf("invalid chunk width %d: must be positive", opts.ChunkWidth)
+	}
+
+	if opts.ChunkHeight <= 0 {
+		return fmt.Errorf("invalid chunk height %d: must be positive", opts.ChunkHeight)
+	}
+
+	if opts.Padding < 0 {
+		return fmt.Errorf("invalid padding %d: must not be negative", opts.Padding)
+	}
+
+	switch opts.EmptyChunks {
+	case KeepEmptyChunks, SkipEmptyChunks, MarkEmptyChunks:
+	default:
+		return fmt.Errorf("unsupported empty chunk policy '%s'", opts.EmptyChunks)
+	}
+
+	switch opts.Objects {
+	case ObjectsByOrigin, ObjectsByCentroid, DuplicateObjects, ClipObjects:
+	default:
+		return fmt.Errorf("unsupported object policy '%s'", opts.Objects)
+	}
+
+	switch opts.ObjectIDs {
+	case KeepObjectIDs, RenumberObjectIDs:
+	default:
+		return fmt.Errorf("unsupported object id policy '%s'", opts.ObjectIDs)
+	}
+
+	if opts.Compression != nil {
+		switch *opts.Compression {
+		case NoCompression, Zlib, Gzip, Zstd:
+		default:
+			return fmt.Errorf("unsupported compression '%s'", *opts.Compression)
+		}
+	}
+
+	if opts.Encoding != nil {
+		switch *opts.Encoding {
+		case EncodingCSV:
+			if opts.Compression != nil && *opts.Compression != NoCompression {
+				return errors.New("csv encoded layer data cannot be compressed")
+			}
+		case EncodingBase64:
+		default:
+			return fmt.Errorf("unsupported encoding '%s'", *opts.Encoding)
+		}
+	}
+
+	return nil
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// filterLayers applies the layer filters of the options
+func filterLayers(layers []Layer, opts SplitOptions) []Layer {
+	var ret []Layer
+	for _, layer := range layers {
+		if opts.ExcludeHidden && !layer.Visible || containsName(opts.ExcludeLayers, layer.Name) {
+			continue
+		}
+
+		if len(opts.IncludeLayers) == 0 || containsName(opts.IncludeLayers, layer.Name) {
+			include := opts
+			include.IncludeLayers = nil
+			layer.Layers = filterLayers(layer.Layers, include)
+			ret = append(ret, layer)
+			continue
+		}
+
+		if layer.Type == Group {
+			if children := filterLayers(layer.Layers, opts); len(children) > 0 {
+				layer.Layers = children
+				ret = append(ret, layer)
+			}
+		}
+	}
+	return ret
+}
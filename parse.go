@@ -1,6 +1,7 @@
 package tmsplit
 
 import (
+	"context"
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
@@ -14,8 +15,13 @@ import (
 type TilemapDecoder = func(io.Reader) (Tilemap, error)
 
 func ParseJSON(r io.Reader) (Tilemap, error) {
+	return ParseJSONContext(context.Background(), r)
+}
+
+// ParseJSONContext is ParseJSON that stops reading when the context is done
+func ParseJSONContext(ctx context.Context, r io.Reader) (Tilemap, error) {
 	tilemap := Tilemap{}
-	if err := json.NewDecoder(r).Decode(&tilemap); err != nil {
+	if err := json.NewDecoder(contextReader{ctx, "parse json", r}).Decode(&tilemap); err != nil {
 		return Tilemap{}, fmt.Errorf("failed to json decode: %w", err)
 	}
 
@@ -23,73 +29,193 @@ func ParseJSON(r io.Reader) (Tilemap, error) {
 }
 
 func ParseXML(r io.Reader) (Tilemap, error) {
+	return ParseXMLContext(context.Background(), r)
+}
+
+// ParseXMLContext is ParseXML that stops reading when the context is done
+func ParseXMLContext(ctx context.Context, r io.Reader) (Tilemap, error) {
 	tilemap := Tilemap{}
-	if err := xml.NewDecoder(r).Decode(&tilemap); err != nil {
+	if err := xml.NewDecoder(contextReader{ctx, "parse xml", r}).Decode(&tilemap); err != nil {
 		return Tilemap{}, fmt.Errorf("failed to xml decode: %w", err)
 	}
 
 	fixTilesets(&tilemap)
-	fixLayers(&tilemap)
 
 	return tilemap, nil
 }
 
 func fixTilesets(tm *Tilemap) {
 	for tsindex := range tm.Tilesets {
-		ts := &tm.Tilesets[tsindex]
-		ts.Image = ts.ImageXML.Source
-		ts.ImageHeight = ts.ImageXML.Height
-		ts.ImageWidth = ts.ImageXML.Width
-		ts.ImageXML = XMLImage{}
+		fixTileset(&tm.Tilesets[tsindex])
 	}
 }
 
-func fixLayers(tm *Tilemap) {
-	for layerindex := range tm.Layers {
-		layer := &tm.Layers[layerindex]
-		layer.Compression = Compression(layer.XMLData.Compression)
-		layer.Encoding = Encoding(layer.XMLData.Encoding)
-		layer.Data = strings.TrimSpace(layer.XMLData.Data)
-		layer.XMLData = XMLData{}
+func fixTileset(ts *Tileset) {
+	ts.Image = ts.ImageXML.Source
+	ts.ImageHeight = ts.ImageXML.Height
+	ts.ImageWidth = ts.ImageXML.Width
+	ts.ImageXML = XMLImage{}
+
+	// the tiles of image collections have an image each
+	for tileIndex := range ts.Tiles {
+		t := &ts.Tiles[tileIndex]
+		t.Image = t.ImageXML.Source
+		t.ImageHeight = t.ImageXML.Height
+		t.ImageWidth = t.ImageXML.Width
+		t.ImageXML = XMLImage{}
+	}
+}
 
+func fixLayer(layer *Layer, elementName string) error {
+	layer.Compression = Compression(layer.XMLData.Compression)
+	layer.Encoding = Encoding(layer.XMLData.Encoding)
+	layer.Data = strings.TrimSpace(layer.XMLData.Data)
+	for _, c := range layer.XMLData.Chunks {
+		layer.Chunks = append(layer.Chunks, Chunk{
+			Data:          strings.TrimSpace(c.Data),
+			HeightInTiles: c.Height,
+			WidthInTiles:  c.Width,
+			X:             c.X,
+			Y:             c.Y,
+		})
+	}
+	if len(layer.Chunks) > 0 {
+		layer.Data = ""
+
+		// tmx has no startx and starty, Tiled writes the top left chunk position to json
+		layer.StartX, layer.StartY = layer.Chunks[0].X, layer.Chunks[0].Y
+		for _, c := range layer.Chunks[1:] {
+			if c.X < layer.StartX {
+				layer.StartX = c.X
+			}
+			if c.Y < layer.StartY {
+				layer.StartY = c.Y
+			}
+		}
+	}
+	layer.XMLData = XMLData{}
+
+	if layer.Encoding == EncodingCSV {
+		// normalise whitespace so csv data matches what ParseJSON produces
+		if err := normalizeCSV(&layer.Data); err != nil {
+			return err
+		}
+		for chunkIndex := range layer.Chunks {
+			if err := normalizeCSV(&layer.Chunks[chunkIndex].Data); err != nil {
+				return err
+			}
+		}
+	}
+
+	switch elementName {
+	case "layer":
 		layer.Type = TileLayer
 
 		// if layer.DrawOrder == "" {
 		// 	layer.DrawOrder = DrawOrderTopDown
 		// }
 
-		if layer.Opacity == 0 {
-			// Really should differentiate between 0 and missing from XML
-			layer.Opacity = 1
-		}
-
-		if !layer.Visible {
-			// Really should differentiate between false and missing from XML
-			// as it stands, a layer can never be invisible :(
-			layer.Visible = true
-		}
-	}
-
-	for _, layer := range tm.XMLObjectGroup {
+	case "objectgroup":
 		layer.Type = ObjectGroup
 
 		if layer.DrawOrder == "" {
 			layer.DrawOrder = DrawOrderTopDown
 		}
 
-		if layer.Opacity == 0 {
-			// Really should differentiate between 0 and missing from XML
-			layer.Opacity = 1
+		if layer.Objects == nil {
+			layer.Objects = []Object{}
 		}
 
-		if !layer.Visible {
-			// Really should differentiate between false and missing from XML
-			// as it stands, a layer can never be invisible :(
-			layer.Visible = true
+	case "imagelayer":
+		layer.Type = ImageLayer
+
+	case "group":
+		layer.Type = Group
+	}
+
+	return nil
+}
+
+func normalizeCSV(data *string) error {
+	if *data == "" {
+		return nil
+	}
+
+	gids, err := parseCSV(*data)
+	if err != nil {
+		return err
+	}
+	*data = formatCSV(gids)
+	return nil
+}
+
+// xmlLayer decodes any of the TMX layer elements, keeping the document order when used for ",any" fields
+type xmlLayer struct {
+	Layer
+	ok bool
+}
+
+func (xl *xmlLayer) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	switch start.Name.Local {
+	case "layer", "objectgroup", "imagelayer", "group":
+		xl.ok = true
+		return d.DecodeElement(&xl.Layer, &start)
+	}
+
+	return d.Skip()
+}
+
+func xmlLayers(decoded []xmlLayer) []Layer {
+	var layers []Layer
+	for _, xl := range decoded {
+		if xl.ok {
+			layers = append(layers, xl.Layer)
 		}
+	}
+	return layers
+}
+
+func (tm *Tilemap) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type alias Tilemap
+	buf := struct {
+		*alias
+		Layers []xmlLayer `xml:",any"`
+	}{alias: (*alias)(tm)}
+
+	if err := d.DecodeElement(&buf, &start); err != nil {
+		return err
+	}
+
+	tm.Layers = xmlLayers(buf.Layers)
+
+	return nil
+}
+
+func (l *Layer) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type alias Layer
+	buf := struct {
+		*alias
+		Opacity *float64   `xml:"opacity,attr"`
+		Visible *bool      `xml:"visible,attr"`
+		Image   XMLImage   `xml:"image"`
+		Layers  []xmlLayer `xml:",any"`
+	}{alias: (*alias)(l)}
 
-		tm.Layers = append(tm.Layers, layer)
+	if err := d.DecodeElement(&buf, &start); err != nil {
+		return err
+	}
+
+	// Tiled leaves out the attributes when they have their default value
+	l.Opacity = 1
+	if buf.Opacity != nil {
+		l.Opacity = *buf.Opacity
 	}
+	l.Visible = buf.Visible == nil || *buf.Visible
+
+	l.Image = buf.Image.Source
+	l.Layers = xmlLayers(buf.Layers)
+
+	return fixLayer(l, start.Name.Local)
 }
 
 func parsePointList(l string) []Point {
@@ -119,8 +245,8 @@ func (o *Object) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 		Width      float64    `xml:"width,attr"`
 		Height     float64    `xml:"height,attr"`
 		Rotation   float64    `xml:"rotation,attr"`
-		GID        int        `xml:"gid,attr"`
-		Visible    bool       `xml:"visible,attr"`
+		GID        GID        `xml:"gid,attr"`
+		Visible    *bool      `xml:"visible,attr"`
 		Template   string     `xml:"template,attr"`
 		Properties []Property `xml:"properties>property"`
 		Ellipse    *struct{}  `xml:"ellipse"`
@@ -148,15 +274,10 @@ func (o *Object) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 	o.Height = buf.Height
 	o.Rotation = buf.Rotation
 	o.GID = buf.GID
-	o.Visible = buf.Visible
+	o.Visible = buf.Visible == nil || *buf.Visible
 	o.Template = buf.Template
 	o.Properties = buf.Properties
 
-	if !o.Visible {
-		// Unfortunate that we don't differentiate between missing value (where true is default) and actual `false`
-		o.Visible = true
-	}
-
 	if buf.Ellipse != nil {
 		o.Ellipse = true
 	} else if buf.Point != nil {
@@ -0,0 +1,47 @@
+package tmsplit
+
+import (
+	"os"
+	"path"
+	"reflect"
+	"testing"
+)
+
+func parseFixture(t *testing.T, name string) Tilemap {
+	t.Helper()
+
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	parse := ParseJSON
+	if path.Ext(name) == ".tmx" {
+		parse = ParseXML
+	}
+
+	tm, err := parse(f)
+	if err != nil {
+		t.Fatalf("failed to parse '%s': %v", name, err)
+	}
+	return tm
+}
+
+// the fixtures are saved from the same map by Tiled
+func TestParseXMLJSONParity(t *testing.T) {
+	for _, name := range []string{"finite", "infinite"} {
+		t.Run(name, func(t *testing.T) {
+			xml := parseFixture(t, "testdata/"+name+".tmx")
+			json := parseFixture(t, "testdata/"+name+".json")
+
+			if len(xml.Layers) != 3 {
+				t.Fatalf("got %d layers, want 3", len(xml.Layers))
+			}
+
+			if !reflect.DeepEqual(xml, json) {
+				t.Errorf("tmx and json differ:\ntmx  %+v\njson %+v", xml, json)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package tmsplit
+
+import "math"
+
+// isStaggered reports if every other row or column of the map is shifted, which is the case for staggered and hexagonal maps
+func isStaggered(tilemap Tilemap) bool {
+	return tilemap.Orientation == Staggered || tilemap.Orientation == Hexagonal
+}
+
+// staggerMetrics returns the distance between the columns and rows of a staggered map and the part of the tiles
+// sticking out of them, in pixels, the way Tiled computes them
+func staggerMetrics(tilemap Tilemap) (columnWidth, rowHeight, sideOffsetX, sideOffsetY int) {
+	var sideLengthX, sideLengthY int
+	if tilemap.Orientation == Hexagonal {
+		if tilemap.StaggerAxis == "x" {
+			sideLengthX = tilemap.HexSideLength
+		} else {
+			sideLengthY = tilemap.HexSideLength
+		}
+	}
+
+	sideOffsetX = (tilemap.TileWidth - sideLengthX) / 2
+	sideOffsetY = (tilemap.TileHeight - sideLengthY) / 2
+	return sideOffsetX + sideLengthX, sideOffsetY + sideLengthY, sideOffsetX, sideOffsetY
+}
+
+// objectTileSize returns the size of a tile in object coordinates. The objects of isometric maps
+// are positioned in a space where tiles are TileHeight wide and high. On staggered maps the tiles
+// overlap and the size is the distance between columns or rows along the stagger axis.
+func objectTileSize(tilemap Tilemap) (float64, float64) {
+	if tilemap.Orientation == Isometric {
+		return float64(tilemap.TileHeight), float64(tilemap.TileHeight)
+	}
+
+	if isStaggered(tilemap) {
+		columnWidth, rowHeight, _, _ := staggerMetrics(tilemap)
+		if tilemap.StaggerAxis == "x" {
+			return float64(columnWidth), float64(tilemap.TileHeight)
+		}
+		return float64(tilemap.TileWidth), float64(rowHeight)
+	}
+
+	return float64(tilemap.TileWidth), float64(tilemap.TileHeight)
+}
+
+// mapObjectSize returns the size of the map in object coordinates, which on staggered maps is more than the size
+// of its tiles as the shifted tiles of the last row or column stick out
+func mapObjectSize(tilemap Tilemap) Point {
+	size := tileToObject(tilemap.WidthInTiles, tilemap.HeightInTiles, tilemap)
+	if !isStaggered(tilemap) {
+		return size
+	}
+
+	columnWidth, rowHeight, sideOffsetX, sideOffsetY := staggerMetrics(tilemap)
+	if tilemap.StaggerAxis == "x" {
+		size.X += float64(sideOffsetX)
+		if tilemap.WidthInTiles > 1 {
+			size.Y += float64(rowHeight)
+		}
+	} else {
+		size.Y += float64(sideOffsetY)
+		if tilemap.HeightInTiles > 1 {
+			size.X += float64(columnWidth)
+		}
+	}
+	return size
+}
+
+// shiftStagger keeps the stagger of the tiles when the map is made to start at the tile position in it,
+// a map starting at an odd column or row staggers the other columns or rows
+func shiftStagger(tilemap *Tilemap, x, y int) {
+	if !isStaggered(*tilemap) {
+		return
+	}
+
+	offset := y
+	if tilemap.StaggerAxis == "x" {
+		offset = x
+	}
+
+	if offset&1 == 1 {
+		// Tiled staggers odd columns or rows by default
+		if tilemap.StaggerIndex == "even" {
+			tilemap.StaggerIndex = "odd"
+		} else {
+			tilemap.StaggerIndex = "even"
+		}
+	}
+}
+
+// tileToObject returns the position of the top left corner of the tile in object coordinates
+func tileToObject(x, y int, tilemap Tilemap) Point {
+	w, h := objectTileSize(tilemap)
+	return Point{float64(x) * w, float64(y) * h}
+}
+
+// objectToTile returns the tile containing the position in object coordinates. On staggered maps this is
+// the tile of the column and row spacing, not the overlapping tile drawn at the position.
+func objectToTile(p Point, tilemap Tilemap) (int, int) {
+	w, h := objectTileSize(tilemap)
+	x, y := int(p.X/w), int(p.Y/h)
+
+	// positions in the part of the last column or row sticking out belong to it
+	size := mapObjectSize(tilemap)
+	if x >= tilemap.WidthInTiles && p.X < size.X {
+		x = tilemap.WidthInTiles - 1
+	}
+	if y >= tilemap.HeightInTiles && p.Y < size.Y {
+		y = tilemap.HeightInTiles - 1
+	}
+	return x, y
+}
+
+// objectToPixel returns the rendered position of a position in object coordinates, relative to the rendered
+// top left corner of tile 0,0, which is the top corner of the tile on isometric maps. Objects of other maps
+// are positioned in rendered pixels.
+func objectToPixel(p Point, tilemap Tilemap) Point {
+	if tilemap.Orientation != Isometric {
+		return p
+	}
+
+	x, y := p.X/float64(tilemap.TileHeight), p.Y/float64(tilemap.TileHeight)
+	return Point{(x - y) * float64(tilemap.TileWidth) / 2, (x + y) * float64(tilemap.TileHeight) / 2}
+}
+
+// chunkPixelOrigin returns the rendered position of the top left corner of the bounds of a chunk with the size in tiles
+// at the tile position, relative to the rendered top left corner of tile 0,0
+func chunkPixelOrigin(x, y, heightInTiles int, tilemap Tilemap) (int, int) {
+	p := objectToPixel(tileToObject(x, y, tilemap), tilemap)
+	if tilemap.Orientation == Isometric {
+		// the left corner of the bottom left tile sticks out furthest
+		p.X -= float64(heightInTiles*tilemap.TileWidth) / 2
+	}
+	return int(math.Floor(p.X)), int(math.Floor(p.Y))
+}
@@ -0,0 +1,261 @@
+package tmsplit
+
+import (
+	"testing"
+)
+
+// isoMap is an isometric map of 4x4 tiles of 32x16 pixels, whose objects are positioned in tiles of 16x16
+func isoMap(objects ...Object) Tilemap {
+	for i := range objects {
+		objects[i].ID = i + 1
+		objects[i].Visible = true
+	}
+	return Tilemap{
+		WidthInTiles: 4, HeightInTiles: 4, TileWidth: 32, TileHeight: 16,
+		Orientation: Isometric, RenderOrder: "right-down", NextLayerID: 2, NextObjectID: len(objects) + 1,
+		Layers: []Layer{
+			{ID: 1, Name: "objects", Type: ObjectGroup, DrawOrder: DrawOrderTopDown, Opacity: 1, Visible: true, Objects: objects},
+		},
+	}
+}
+
+func TestIsometricObjectToPixel(t *testing.T) {
+	tm := isoMap()
+
+	tests := []struct {
+		object, pixel Point
+	}{
+		{Point{0, 0}, Point{0, 0}},
+		{Point{16, 0}, Point{16, 8}},
+		{Point{0, 16}, Point{-16, 8}},
+		{Point{32, 32}, Point{0, 32}},
+		{Point{40, 24}, Point{16, 32}},
+	}
+
+	for _, tt := range tests {
+		if got := objectToPixel(tt.object, tm); !pointsEqual([]Point{got}, []Point{tt.pixel}) {
+			t.Errorf("%v: got %v, want %v", tt.object, got, tt.pixel)
+		}
+	}
+}
+
+func TestIsometricChunkPixelOrigin(t *testing.T) {
+	tm := isoMap()
+
+	tests := []struct {
+		x, y, height int
+		pixelX       int
+		pixelY       int
+	}{
+		// the left corner of the bottom left tile and the top corner of the top left tile
+		{0, 0, 2, -32, 0},
+		{2, 0, 2, 0, 16},
+		{0, 2, 2, -64, 16},
+		{2, 2, 2, -32, 32},
+		{1, 3, 1, -48, 32},
+	}
+
+	for _, tt := range tests {
+		x, y := chunkPixelOrigin(tt.x, tt.y, tt.height, tm)
+		if x != tt.pixelX || y != tt.pixelY {
+			t.Errorf("%d,%d: got %d,%d, want %d,%d", tt.x, tt.y, x, y, tt.pixelX, tt.pixelY)
+		}
+	}
+}
+
+func TestIsometricSplit(t *testing.T) {
+	spawn := Object{Name: "spawn", Type: "spawn", X: 40, Y: 24, Point: true}
+	box := Object{Name: "box", X: 40, Y: 40, Width: 8, Height: 8}
+	tm := isoMap(spawn, box)
+
+	chunks, err := Split(tm, SplitOptions{ChunkWidth: 2, ChunkHeight: 2})
+	if err != nil {
+		t.Fatalf("failed to split: %v", err)
+	}
+	master, err := CreateMasterFile(chunks, "iso.json")
+	if err != nil {
+		t.Fatalf("failed to create master file: %v", err)
+	}
+
+	// the spawn is in rendered pixels of the whole map
+	if master.Spawn != (Spawn{X: 16, Y: 32}) {
+		t.Errorf("got spawn %+v, want 16,32", master.Spawn)
+	}
+
+	pixels := [][2]int{{-32, 0}, {0, 16}, {-64, 16}, {-32, 32}}
+	for i, entry := range master.Tilemaps {
+		if entry.PixelX != pixels[i][0] || entry.PixelY != pixels[i][1] {
+			t.Errorf("tilemap %d: got pixel position %d,%d, want %d,%d", i, entry.PixelX, entry.PixelY, pixels[i][0], pixels[i][1])
+		}
+	}
+
+	// objects are moved by the object position of the top left tile of their chunk
+	want := map[int][]Point{1: {{8, 24}}, 3: {{8, 8}}}
+	for chunkIndex, chunk := range chunks {
+		var got []Point
+		for _, o := range chunk.Layers[0].Objects {
+			got = append(got, Point{o.X, o.Y})
+		}
+		if !pointsEqual(got, want[chunkIndex]) {
+			t.Errorf("chunk %d: got objects at %v, want %v", chunkIndex, got, want[chunkIndex])
+		}
+	}
+}
+
+// Tiled draws the tile objects of isometric maps around the bottom centre of their position
+func TestIsometricTileObjects(t *testing.T) {
+	tile := Object{X: 40, Y: 40, Width: 32, Height: 48, GID: 1}
+
+	for _, policy := range []ObjectPolicy{ObjectsByOrigin, ObjectsByCentroid, DuplicateObjects, ClipObjects} {
+		chunks, err := Split(isoMap(tile), SplitOptions{ChunkWidth: 1, ChunkHeight: 1, Objects: policy})
+		if err != nil {
+			t.Fatalf("failed to split: %v", err)
+		}
+
+		for chunkIndex, chunk := range chunks {
+			want := 0
+			if chunkIndex == 2*4+2 {
+				want = 1
+			}
+			if got := len(chunk.Layers[0].Objects); got != want {
+				t.Errorf("policy '%s', chunk %d: got %d objects, want %d", policy, chunkIndex, got, want)
+			}
+		}
+	}
+}
+
+// staggerMap is a staggered or hexagonal map of 4x4 tiles of 32x16 pixels
+func staggerMap(orientation Orientation, axis, index string, sideLength int, objects ...Object) Tilemap {
+	tm := isoMap(objects...)
+	tm.Orientation = orientation
+	tm.StaggerAxis = axis
+	tm.StaggerIndex = index
+	tm.HexSideLength = sideLength
+	return tm
+}
+
+func TestSplitStaggerIndex(t *testing.T) {
+	tests := []struct {
+		axis, index string
+		// object is on tile 3,3 of both staggered and hexagonal maps
+		object Object
+		// want is the stagger index of the chunks of 3x3 tiles, the chunks at the odd column or row 3 stagger the other tiles
+		want [4]string
+	}{
+		{"y", "odd", Object{X: 100, Y: 38, Point: true}, [4]string{"odd", "odd", "even", "even"}},
+		{"y", "even", Object{X: 100, Y: 38, Point: true}, [4]string{"even", "even", "odd", "odd"}},
+		{"x", "odd", Object{X: 62, Y: 50, Point: true}, [4]string{"odd", "even", "odd", "even"}},
+		{"x", "even", Object{X: 62, Y: 50, Point: true}, [4]string{"even", "odd", "even", "odd"}},
+	}
+
+	for _, tt := range tests {
+		for _, orientation := range []Orientation{Staggered, Hexagonal} {
+			tm := staggerMap(orientation, tt.axis, tt.index, 8, tt.object)
+
+			chunks, err := Split(tm, SplitOptions{ChunkWidth: 3, ChunkHeight: 3, EmptyChunks: MarkEmptyChunks})
+			if err != nil {
+				t.Fatalf("failed to split: %v", err)
+			}
+
+			for chunkIndex, chunk := range chunks {
+				if chunk.StaggerIndex != tt.want[chunkIndex] {
+					t.Errorf("%s %s %s: chunk %d has stagger index '%s', want '%s'", orientation, tt.axis, tt.index, chunkIndex, chunk.StaggerIndex, tt.want[chunkIndex])
+				}
+			}
+
+			// the only chunk that isn't empty is the last one, at column and row 3
+			master, err := CreateMasterFile(chunks, "stagger.json")
+			if err != nil {
+				t.Fatalf("failed to create master file: %v", err)
+			}
+			merged, err := Merge(master, chunks)
+			if err != nil {
+				t.Fatalf("failed to merge: %v", err)
+			}
+			if merged.StaggerIndex != tt.index {
+				t.Errorf("%s %s %s: merged map has stagger index '%s'", orientation, tt.axis, tt.index, merged.StaggerIndex)
+			}
+		}
+	}
+}
+
+func TestSplitHexagonal(t *testing.T) {
+	tests := []struct {
+		name string
+		axis string
+		// object is positioned in the third column or row, which starts at 48 pixels with the side length of 16
+		object Object
+		chunk  int
+		local  Point
+	}{
+		{"rows", "y", Object{X: 8, Y: 50, Point: true}, 8, Point{8, 2}},
+		{"columns", "x", Object{X: 50, Y: 8, Point: true}, 2, Point{2, 8}},
+	}
+
+	for _, tt := range tests {
+		tm := staggerMap(Hexagonal, tt.axis, "odd", 16, tt.object)
+		tm.TileHeight = 32
+
+		chunks, err := Split(tm, SplitOptions{ChunkWidth: 1, ChunkHeight: 1})
+		if err != nil {
+			t.Fatalf("failed to split: %v", err)
+		}
+
+		for chunkIndex, chunk := range chunks {
+			objects := chunk.Layers[0].Objects
+			if chunkIndex != tt.chunk {
+				if len(objects) != 0 {
+					t.Errorf("%s: chunk %d has %d objects, want none", tt.name, chunkIndex, len(objects))
+				}
+				continue
+			}
+
+			if len(objects) != 1 || !pointsEqual([]Point{{objects[0].X, objects[0].Y}}, []Point{tt.local}) {
+				t.Errorf("%s: chunk %d has objects %v, want one at %v", tt.name, chunkIndex, objects, tt.local)
+			}
+		}
+	}
+}
+
+// the shifted tiles of the last row or column stick out of the map, objects on them stay whole in the chunks along the edge
+func TestSplitStaggerOverhang(t *testing.T) {
+	tests := []struct {
+		name        string
+		orientation Orientation
+		axis        string
+		object      Object
+	}{
+		// rows are 8 pixels apart and the shifted last row sticks out 16 pixels to the right and 8 below
+		{"staggered rows", Staggered, "y", Object{X: 130, Y: 33, Width: 12, Height: 6}},
+		// columns are 16 pixels apart and the shifted last column sticks out 16 pixels to the right and 8 below
+		{"staggered columns", Staggered, "x", Object{X: 66, Y: 65, Width: 12, Height: 6}},
+		// with the side length of 8 rows are 12 pixels apart and the last row sticks out 16 pixels to the right and 4 below
+		{"hexagonal rows", Hexagonal, "y", Object{X: 130, Y: 49, Width: 12, Height: 2}},
+	}
+
+	for _, tt := range tests {
+		for _, policy := range []ObjectPolicy{ObjectsByOrigin, ObjectsByCentroid, DuplicateObjects, ClipObjects} {
+			tm := staggerMap(tt.orientation, tt.axis, "odd", 8, tt.object)
+
+			chunks, err := Split(tm, SplitOptions{ChunkWidth: 2, ChunkHeight: 2, Objects: policy})
+			if err != nil {
+				t.Fatalf("failed to split: %v", err)
+			}
+
+			for chunkIndex, chunk := range chunks {
+				want := 0
+				if chunkIndex == 3 {
+					want = 1
+				}
+				objects := chunk.Layers[0].Objects
+				if len(objects) != want {
+					t.Errorf("%s, policy '%s': chunk %d has %d objects, want %d", tt.name, policy, chunkIndex, len(objects), want)
+					continue
+				}
+				if want == 1 && (objects[0].Width != tt.object.Width || len(objects[0].Properties) != 0) {
+					t.Errorf("%s, policy '%s': object isn't whole in chunk %d: %+v", tt.name, policy, chunkIndex, objects[0])
+				}
+			}
+		}
+	}
+}
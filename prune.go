@@ -0,0 +1,113 @@
+package tmsplit
+
+import "sort"
+
+// tilesetIndex returns the index of the tileset containing the gid, or -1 if there is none
+func tilesetIndex(tilesets []Tileset, gid GID) int {
+	id := int(gid.TileID())
+	if id == 0 {
+		return -1
+	}
+
+	i := sort.Search(len(tilesets), func(i int) bool { return tilesets[i].FirstGID > id })
+	return i - 1
+}
+
+// tilesetSpan returns the number of gids the tileset takes up, nextFirstGID is the first gid of the next tileset or 0.
+// Image collection tilesets can have tile ids beyond their tile count and the tile count of external tilesets
+// isn't known, so the span covers the highest tile id and the gids up to the next tileset as well.
+func tilesetSpan(ts Tileset, nextFirstGID int) int {
+	span := ts.TileCount
+	for _, tile := range ts.Tiles {
+		if tile.ID+1 > span {
+			span = tile.ID + 1
+		}
+	}
+	if nextFirstGID-ts.FirstGID > span {
+		span = nextFirstGID - ts.FirstGID
+	}
+	return span
+}
+
+// nextFirstGID returns the first gid of the tileset after tileset i of the tilesets sorted by first gid, or 0
+func nextFirstGID(tilesets []Tileset, i int) int {
+	if i+1 < len(tilesets) {
+		return tilesets[i+1].FirstGID
+	}
+	return 0
+}
+
+// usedObjectTilesets marks the tilesets of the tile objects in the layers
+func usedObjectTilesets(layers []Layer, tilesets []Tileset, used []bool) {
+	for _, layer := range layers {
+		for _, object := range layer.Objects {
+			if i := tilesetIndex(tilesets, object.GID); i >= 0 {
+				used[i] = true
+			}
+		}
+		usedObjectTilesets(layer.Layers, tilesets, used)
+	}
+}
+
+// remapObjectGIDs applies remap to the gid of the tile objects in the layers
+func remapObjectGIDs(layers []Layer, remap func(GID) GID) {
+	for layerIndex := range layers {
+		layer := &layers[layerIndex]
+		for objectIndex := range layer.Objects {
+			if gid := layer.Objects[objectIndex].GID; gid != 0 {
+				layer.Objects[objectIndex].GID = remap(gid)
+			}
+		}
+		remapObjectGIDs(layer.Layers, remap)
+	}
+}
+
+// pruneTilesets removes the tilesets the chunk doesn't use and renumbers the gids of the remaining ones
+// so they follow each other from 1. The flip flags of the gids are kept.
+func pruneTilesets(tm *Tilemap, data []chunkLayerData) {
+	tilesets := tm.Tilesets
+	sort.SliceStable(tilesets, func(i, j int) bool { return tilesets[i].FirstGID < tilesets[j].FirstGID })
+
+	used := make([]bool, len(tilesets))
+	for _, d := range data {
+		for _, gid := range d.data {
+			if i := tilesetIndex(tilesets, gid); i >= 0 {
+				used[i] = true
+			}
+		}
+	}
+	usedObjectTilesets(tm.Layers, tilesets, used)
+
+	// offsets[i] is added to the gids of tileset i
+	offsets := make([]int, len(tilesets))
+	var pruned []Tileset
+	firstgid := 1
+	for i, ts := range tilesets {
+		if !used[i] {
+			continue
+		}
+
+		span := tilesetSpan(ts, nextFirstGID(tilesets, i))
+		offsets[i] = firstgid - ts.FirstGID
+		ts.FirstGID = firstgid
+		pruned = append(pruned, ts)
+		firstgid += span
+	}
+
+	remap := func(gid GID) GID {
+		i := tilesetIndex(tilesets, gid)
+		if i < 0 {
+			return gid
+		}
+		return gid.WithTileID(uint32(int(gid.TileID()) + offsets[i]))
+	}
+
+	for _, d := range data {
+		for i, gid := range d.data {
+			d.data[i] = remap(gid)
+		}
+	}
+	remapObjectGIDs(tm.Layers, remap)
+
+	tm.Tilesets = pruned
+}
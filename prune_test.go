@@ -0,0 +1,86 @@
+package tmsplit
+
+import (
+	"reflect"
+	"testing"
+)
+
+// sparseTilemap has an image collection tileset with tile ids beyond its tile count, like Tiled leaves
+// after removing tiles from a collection
+func sparseTilemap() Tilemap {
+	return Tilemap{
+		WidthInTiles: 3, HeightInTiles: 1, TileWidth: 16, TileHeight: 16,
+		Orientation: Orthogonal, RenderOrder: "right-down", NextLayerID: 2, NextObjectID: 1,
+		Layers: []Layer{
+			{ID: 1, Name: "ground", Type: TileLayer, WidthInTiles: 3, HeightInTiles: 1, Opacity: 1, Visible: true,
+				Encoding: EncodingCSV, Data: "6,7,0"},
+		},
+		Tilesets: []Tileset{
+			{FirstGID: 1, Name: "icons", TileWidth: 16, TileHeight: 16, TileCount: 2,
+				Tiles: []Tile{{ID: 0, Image: "a.png"}, {ID: 5, Image: "f.png"}}},
+			{FirstGID: 7, Name: "ts", TileWidth: 16, TileHeight: 16, TileCount: 16, Columns: 4, Image: "ts.png"},
+			{FirstGID: 23, Name: "unused", TileWidth: 16, TileHeight: 16, TileCount: 4, Columns: 2, Image: "unused.png"},
+		},
+	}
+}
+
+func TestTilesetSpan(t *testing.T) {
+	tilesets := sparseTilemap().Tilesets
+
+	tests := []struct {
+		name string
+		ts   Tileset
+		next int
+		want int
+	}{
+		{"tile count", tilesets[1], 0, 16},
+		{"sparse ids", tilesets[0], 0, 6},
+		{"gap to next", tilesets[1], 30, 23},
+		{"external", Tileset{FirstGID: 5, Source: "ext.tsx"}, 12, 7},
+		{"external last", Tileset{FirstGID: 5, Source: "ext.tsx"}, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tilesetSpan(tt.ts, tt.next); got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPruneSparseTilesets(t *testing.T) {
+	tests := []struct {
+		width    int
+		tilesets [][]string
+		gids     [][]GID
+	}{
+		{1, [][]string{{"icons"}, {"ts"}, nil}, [][]GID{{6}, {1}, {0}}},
+		{3, [][]string{{"icons", "ts"}}, [][]GID{{6, 7, 0}}},
+	}
+
+	for _, tt := range tests {
+		chunks, err := Split(sparseTilemap(), SplitOptions{ChunkWidth: tt.width, ChunkHeight: 1, PruneTilesets: true})
+		if err != nil {
+			t.Fatalf("failed to split: %v", err)
+		}
+
+		for i, chunk := range chunks {
+			var names []string
+			for _, ts := range chunk.Tilesets {
+				names = append(names, ts.Name)
+			}
+			if !reflect.DeepEqual(names, tt.tilesets[i]) {
+				t.Errorf("width %d, chunk %d: got tilesets %v, want %v", tt.width, i, names, tt.tilesets[i])
+			}
+
+			grid, err := chunk.Layers[0].Tiles()
+			if err != nil {
+				t.Fatalf("failed to decode: %v", err)
+			}
+			if !reflect.DeepEqual(grid.GIDs, tt.gids[i]) {
+				t.Errorf("width %d, chunk %d: got gids %v, want %v", tt.width, i, grid.GIDs, tt.gids[i])
+			}
+		}
+	}
+}
@@ -3,25 +3,73 @@ package tmsplit
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/binary"
-	"encoding/json"
 	"fmt"
 	"math"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/sirupsen/logrus"
 )
 
-func decodeLayerData(b64enc string) ([]uint32, error) {
-	b, err := base64.StdEncoding.DecodeString(b64enc)
+func parseCSV(data string) ([]GID, error) {
+	var gids []GID
+	for _, field := range strings.Split(data, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		gid, err := strconv.ParseUint(field, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse csv gid '%s': %w", field, err)
+		}
+		gids = append(gids, GID(gid))
+	}
+
+	return gids, nil
+}
+
+func formatCSV(data []GID) string {
+	fields := make([]string, len(data))
+	for i, gid := range data {
+		fields[i] = strconv.FormatUint(uint64(gid), 10)
+	}
+
+	return strings.Join(fields, ",")
+}
+
+func decodeLayerData(layer Layer) ([]GID, error) {
+	switch layer.Encoding {
+	case EncodingCSV:
+		if layer.Compression != NoCompression {
+			return nil, fmt.Errorf("csv layer data cannot be compressed")
+		}
+		return parseCSV(layer.Data)
+
+	case EncodingBase64:
+	default:
+		return nil, fmt.Errorf("unsupported encoding '%s'", layer.Encoding)
+	}
+
+	b, err := base64.StdEncoding.DecodeString(layer.Data)
 	if err != nil {
 		return nil, err
 	}
 
-	var ui32 uint32
-	var decodedSize = binary.Size(&ui32)
+	b, err = decompress(b, layer.Compression)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress layer data: %w", err)
+	}
+
+	var gid GID
+	var decodedSize = binary.Size(&gid)
 
-	layerData := make([]uint32, len(b)/decodedSize)
+	layerData := make([]GID, len(b)/decodedSize)
 	if err := binary.Read(bytes.NewBuffer(b), binary.LittleEndian, layerData); err != nil {
 		return nil, err
 	}
@@ -29,17 +77,34 @@ func decodeLayerData(b64enc string) ([]uint32, error) {
 	return layerData, nil
 }
 
-func encodeLayerData(data []uint32) (string, error) {
+func encodeLayerData(data []GID, encoding Encoding, compression Compression) (string, error) {
+	switch encoding {
+	case EncodingCSV:
+		if compression != NoCompression {
+			return "", fmt.Errorf("csv layer data cannot be compressed")
+		}
+		return formatCSV(data), nil
+
+	case EncodingBase64:
+	default:
+		return "", fmt.Errorf("unsupported encoding '%s'", encoding)
+	}
+
 	buf := bytes.Buffer{}
 	if err := binary.Write(&buf, binary.LittleEndian, data); err != nil {
 		return "", err
 	}
 
-	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+	b, err := compress(buf.Bytes(), compression)
+	if err != nil {
+		return "", fmt.Errorf("failed to compress layer data: %w", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(b), nil
 }
 
-func flattenMap(data [][]uint32, width int) []uint32 {
-	ret := make([]uint32, len(data)*len(data[0]))
+func flattenMap(data [][]GID, width int) []GID {
+	ret := make([]GID, len(data)*len(data[0]))
 	for row, cols := range data {
 		for col, gid := range cols {
 			ret[row*width+col] = gid
@@ -49,12 +114,53 @@ func flattenMap(data [][]uint32, width int) []uint32 {
 	return ret
 }
 
-func countLayerType(tm Tilemap, layerType LayerType) int {
+// decodedLayer holds the decoded tile layer data of a layer and, for groups, its children
+type decodedLayer struct {
+	data   []GID
+	layers []decodedLayer
+}
+
+// decodeLayers decodes the tile data of the layers, which must cover the map of width by height tiles
+func decodeLayers(ctx context.Context, layers []Layer, width, height int) ([]decodedLayer, error) {
+	decoded := make([]decodedLayer, len(layers))
+	for layerIndex, layer := range layers {
+		if err := checkContext(ctx, "split"); err != nil {
+			return nil, err
+		}
+
+		switch layer.Type {
+		case TileLayer:
+			logrus.Debugf("adding layer: %v, %v", layer.Name, layer.Type)
+			data, err := decodeLayerData(layer)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode layer data of '%s': %w", layer.Name, err)
+			}
+
+			logrus.Debugf("decoded %d gids", len(data))
+			if len(data) != width*height {
+				return nil, fmt.Errorf("layer '%s' has %d tiles, expected %dx%d", layer.Name, len(data), width, height)
+			}
+			decoded[layerIndex].data = data
+
+		case Group:
+			children, err := decodeLayers(ctx, layer.Layers, width, height)
+			if err != nil {
+				return nil, err
+			}
+			decoded[layerIndex].layers = children
+		}
+	}
+
+	return decoded, nil
+}
+
+func countLayerType(layers []Layer, layerType LayerType) int {
 	c := 0
-	for _, l := range tm.Layers {
+	for _, l := range layers {
 		if l.Type == layerType {
 			c++
 		}
+		c += countLayerType(l.Layers, layerType)
 	}
 	return c
 }
@@ -66,90 +172,469 @@ func min(a, b int) int {
 	return b
 }
 
-func Split(tilemap Tilemap, chunkHeight, chunkWidth int) ([]Tilemap, error) {
-	logrus.Debugf("tilemap widthInTiles: %d, heightInTiles: %d", tilemap.WidthInTiles, tilemap.HeightInTiles)
-	widthInTilemaps := int(math.Ceil(math.Max(float64(tilemap.WidthInTiles)/float64(chunkHeight), 1.0)))
-	heightInTilemaps := int(math.Ceil(math.Max(float64(tilemap.HeightInTiles)/float64(chunkWidth), 1.0)))
-	logrus.Debugf("widthInTilemaps: %d, heightInTilemaps: %d", widthInTilemaps, heightInTilemaps)
+// chunkRegion is a part of the source map, in tiles
+type chunkRegion struct {
+	left, top, width, height int
+}
 
-	ntilemaps := int(math.Ceil(float64(widthInTilemaps) * float64(heightInTilemaps)))
-	nlayers := countLayerType(tilemap, TileLayer)
-	logrus.Debugf("creating %d tilemap(s) with %d layer(s) each", ntilemaps, nlayers)
+// chunkGrid is the grid of chunks of a map without their padding
+type chunkGrid struct {
+	chunkWidth, chunkHeight int
+	widthInTiles            int
+	heightInTiles           int
+}
 
-	var decodedLayerData [][]uint32
-	for _, layer := range tilemap.Layers {
-		if layer.Type != TileLayer {
-			continue
+// cell returns the region the chunk owning the tile owns
+func (g chunkGrid) cell(x, y int) chunkRegion {
+	left, top := x/g.chunkWidth*g.chunkWidth, y/g.chunkHeight*g.chunkHeight
+	return chunkRegion{
+		left:   left,
+		top:    top,
+		width:  min(g.chunkWidth, g.widthInTiles-left),
+		height: min(g.chunkHeight, g.heightInTiles-top),
+	}
+}
+
+// cells returns the regions owned by the chunks with tiles in the region, row by row
+func (g chunkGrid) cells(region chunkRegion) []chunkRegion {
+	var cells []chunkRegion
+	for top := region.top / g.chunkHeight * g.chunkHeight; top < region.top+region.height; top += g.chunkHeight {
+		for left := region.left / g.chunkWidth * g.chunkWidth; left < region.left+region.width; left += g.chunkWidth {
+			cells = append(cells, g.cell(left, top))
 		}
+	}
+	return cells
+}
 
-		logrus.Debugf("adding layer: %v, %v", layer.Name, layer.Type)
-		data, err := decodeLayerData(layer.Data)
-		if err != nil {
-			return nil, fmt.Errorf("failed to decode layer data: %w", err)
+// layerMetadata copies the layers without their tile data and objects
+func layerMetadata(layers []Layer) []Layer {
+	if layers == nil {
+		return nil
+	}
+
+	ret := make([]Layer, len(layers))
+	for i, l := range layers {
+		ret[i] = l
+		ret[i].Data = ""
+		ret[i].Chunks = nil
+		ret[i].Objects = nil
+		ret[i].Layers = layerMetadata(l.Layers)
+	}
+	return ret
+}
+
+// chunkLayerData is the tile data of a chunk layer, kept decoded until the chunk is complete
+type chunkLayerData struct {
+	layer *Layer
+	data  []GID
+}
+
+// emptyLayers reports if the chunk layers have no tiles and no objects
+func emptyLayers(layers []Layer, data []chunkLayerData) bool {
+	for _, d := range data {
+		for _, gid := range d.data {
+			if gid != 0 {
+				return false
+			}
 		}
+	}
 
-		logrus.Debugf("decoded %d gids", len(data))
-		decodedLayerData = append(decodedLayerData, data)
+	for _, layer := range layers {
+		if len(layer.Objects) > 0 || !emptyLayers(layer.Layers, nil) {
+			return false
+		}
 	}
 
-	var chunkedTilemaps []Tilemap
+	return true
+}
 
-	for chunkIndex := 0; chunkIndex < ntilemaps; chunkIndex++ {
-		buf := bytes.Buffer{}
-		if err := json.NewEncoder(&buf).Encode(&tilemap); err != nil {
-			return nil, fmt.Errorf("failed to encoder original tilemap to json: %w", err)
+// encodeChunkLayers encodes the tile data of the chunk layers
+func encodeChunkLayers(data []chunkLayerData, opts SplitOptions) error {
+	for _, d := range data {
+		layer := d.layer
+
+		encoding := layer.Encoding
+		if opts.Encoding != nil {
+			encoding = *opts.Encoding
 		}
 
-		tm := Tilemap{}
-		if err := json.NewDecoder(&buf).Decode(&tm); err != nil {
-			return nil, fmt.Errorf("failed to decode original tilemap buffer from json: %w", err)
+		compression := layer.Compression
+		if opts.Compression != nil {
+			compression = *opts.Compression
+		} else if encoding == EncodingCSV {
+			compression = NoCompression
 		}
 
-		left := (chunkIndex % widthInTilemaps) * chunkWidth
-		top := (chunkIndex / widthInTilemaps) * chunkHeight
-		tm.WidthInTiles = min(chunkWidth, tilemap.WidthInTiles-left)
-		tm.HeightInTiles = min(chunkHeight, tilemap.HeightInTiles-top)
-		logrus.Debugf("tilemap %d: %d,%d (%dx%d)", chunkIndex, left, top, tm.WidthInTiles, tm.HeightInTiles)
+		encoded, err := encodeLayerData(d.data, encoding, compression)
+		if err != nil {
+			return fmt.Errorf("failed to encode layer data of '%s': %w", layer.Name, err)
+		}
+		layer.Encoding = encoding
+		layer.Compression = compression
+		layer.Data = encoded
+	}
+
+	return nil
+}
+
+// splitLayers fills in the objects of the chunk layers from the source layers and collects their tile data
+func splitLayers(ctx context.Context, layers, source []Layer, decoded []decodedLayer, tilemap Tilemap, grid chunkGrid, region chunkRegion, policy ObjectPolicy, data *[]chunkLayerData) error {
+	chunkoffset := tilemap.WidthInTiles*region.top + region.left
 
-		chunkoffset := tilemap.WidthInTiles*top + left
+	for layerIndex := range layers {
+		if err := checkContext(ctx, "split"); err != nil {
+			return err
+		}
 
-		for layerIndex, layerData := range decodedLayerData {
-			var ll []uint32
+		layer := &layers[layerIndex]
 
-			for itop := 0; itop < tm.HeightInTiles; itop++ {
+		switch layer.Type {
+		case TileLayer:
+			ll := make([]GID, 0, region.width*region.height)
+			for itop := 0; itop < region.height; itop++ {
 				begin := chunkoffset + itop*tilemap.WidthInTiles
-				end := begin + tm.WidthInTiles
-				ll = append(ll, layerData[begin:end]...)
+				end := begin + region.width
+				ll = append(ll, decoded[layerIndex].data[begin:end]...)
 			}
-			encoded, err := encodeLayerData(ll)
-			if err != nil {
-				return nil, fmt.Errorf("failed to encode layer data: %w", err)
+
+			layer.WidthInTiles = region.width
+			layer.HeightInTiles = region.height
+			*data = append(*data, chunkLayerData{layer: layer, data: ll})
+
+		case ObjectGroup:
+			objects := []Object{}
+			for _, object := range source[layerIndex].Objects {
+				for _, object := range assignObject(object, tilemap, grid, region, policy) {
+					offset := tileToObject(region.left, region.top, tilemap)
+					object = object.Clone()
+					object.X -= offset.X
+					object.Y -= offset.Y
+					objects = append(objects, object)
+				}
+			}
+			layer.Objects = objects
+
+		case Group:
+			if err := splitLayers(ctx, layer.Layers, source[layerIndex].Layers, decoded[layerIndex].layers, tilemap, grid, region, policy, data); err != nil {
+				return err
 			}
-			tm.Layers[layerIndex].WidthInTiles = tm.WidthInTiles
-			tm.Layers[layerIndex].HeightInTiles = tm.HeightInTiles
-			tm.Layers[layerIndex].Data = encoded
 		}
+	}
+
+	return nil
+}
+
+// splitter holds what is shared between the chunks of a Split
+type splitter struct {
+	tilemap          Tilemap
+	metadata         Tilemap
+	decoded          []decodedLayer
+	grid             chunkGrid
+	widthInTilemaps  int
+	heightInTilemaps int
+	opts             SplitOptions
+}
+
+func newSplitter(ctx context.Context, original Tilemap, opts SplitOptions) (*splitter, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
 
-		for layerIndex, layer := range tm.Layers {
-			if layer.Type != ObjectGroup {
-				continue
+	tilemap, err := NormalizeInfinite(original)
+	if err != nil {
+		return nil, fmt.Errorf("failed to normalize infinite map: %w", err)
+	}
+	tilemap.Layers = filterLayers(tilemap.Layers, opts)
+
+	logrus.Debugf("tilemap widthInTiles: %d, heightInTiles: %d", tilemap.WidthInTiles, tilemap.HeightInTiles)
+	widthInTilemaps := int(math.Ceil(math.Max(float64(tilemap.WidthInTiles)/float64(opts.ChunkWidth), 1.0)))
+	heightInTilemaps := int(math.Ceil(math.Max(float64(tilemap.HeightInTiles)/float64(opts.ChunkHeight), 1.0)))
+	logrus.Debugf("widthInTilemaps: %d, heightInTilemaps: %d", widthInTilemaps, heightInTilemaps)
+
+	decoded, err := decodeLayers(ctx, tilemap.Layers, tilemap.WidthInTiles, tilemap.HeightInTiles)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode layer data: %w", err)
+	}
+
+	// chunks share everything but the tile data and objects, which are filled in per chunk
+	metadata := tilemap
+	metadata.Layers = layerMetadata(tilemap.Layers)
+	metadata.Source = SourceMap{
+		TileX:         tilemap.OriginX,
+		TileY:         tilemap.OriginY,
+		WidthInTiles:  tilemap.WidthInTiles,
+		HeightInTiles: tilemap.HeightInTiles,
+		NextObjectID:  tilemap.NextObjectID,
+	}
+
+	return &splitter{
+		tilemap:  tilemap,
+		metadata: metadata,
+		decoded:  decoded,
+		grid: chunkGrid{
+			chunkWidth:    opts.ChunkWidth,
+			chunkHeight:   opts.ChunkHeight,
+			widthInTiles:  tilemap.WidthInTiles,
+			heightInTiles: tilemap.HeightInTiles,
+		},
+		widthInTilemaps:  widthInTilemaps,
+		heightInTilemaps: heightInTilemaps,
+		opts:             opts,
+	}, nil
+}
+
+func (s *splitter) count() int {
+	return s.widthInTilemaps * s.heightInTilemaps
+}
+
+func (s *splitter) chunk(ctx context.Context, chunkIndex int) (Tilemap, error) {
+	tm := s.metadata.Clone()
+
+	owned := s.grid.cell((chunkIndex%s.widthInTilemaps)*s.opts.ChunkWidth, (chunkIndex/s.widthInTilemaps)*s.opts.ChunkHeight)
+
+	// padding is limited by the edges of the map
+	padding := Padding{
+		Left:   min(s.opts.Padding, owned.left),
+		Top:    min(s.opts.Padding, owned.top),
+		Right:  min(s.opts.Padding, s.tilemap.WidthInTiles-owned.left-owned.width),
+		Bottom: min(s.opts.Padding, s.tilemap.HeightInTiles-owned.top-owned.height),
+	}
+
+	region := chunkRegion{
+		left:   owned.left - padding.Left,
+		top:    owned.top - padding.Top,
+		width:  padding.Left + owned.width + padding.Right,
+		height: padding.Top + owned.height + padding.Bottom,
+	}
+
+	tm.WidthInTiles = region.width
+	tm.HeightInTiles = region.height
+	tm.OriginX = s.tilemap.OriginX + region.left
+	tm.OriginY = s.tilemap.OriginY + region.top
+	shiftStagger(&tm, region.left, region.top)
+	logrus.Debugf("tilemap %d: %d,%d (%dx%d)", chunkIndex, region.left, region.top, tm.WidthInTiles, tm.HeightInTiles)
+
+	if s.opts.Padding > 0 {
+		tm.Padding = padding
+		tm.Properties = append(tm.Properties, padding.Properties()...)
+	}
+
+	var data []chunkLayerData
+	if err := splitLayers(ctx, tm.Layers, s.tilemap.Layers, s.decoded, s.tilemap, s.grid, region, s.opts.Objects, &data); err != nil {
+		return Tilemap{}, fmt.Errorf("failed to split chunk %d: %w", chunkIndex, err)
+	}
+
+	if s.opts.EmptyChunks != KeepEmptyChunks {
+		tm.Empty = emptyLayers(tm.Layers, data)
+	}
+
+	numberObjects(&tm, s.opts.ObjectIDs)
+
+	if s.opts.PruneTilesets {
+		pruneTilesets(&tm, data)
+	}
+
+	if err := encodeChunkLayers(data, s.opts); err != nil {
+		return Tilemap{}, fmt.Errorf("failed to split chunk %d: %w", chunkIndex, err)
+	}
+
+	if s.opts.Deduplicate {
+		hash, err := HashChunk(tm)
+		if err != nil {
+			return Tilemap{}, fmt.Errorf("failed to hash chunk %d: %w", chunkIndex, err)
+		}
+		tm.Hash = hash
+	}
+
+	return tm, nil
+}
+
+func workerCount(workers int) int {
+	if workers <= 0 {
+		return runtime.NumCPU()
+	}
+	return workers
+}
+
+// ChunkInfo describes where a chunk created by SplitFunc is placed in the source map
+type ChunkInfo struct {
+	// Index of the chunk, chunks are numbered row by row
+	Index int
+	// ChunkX and ChunkY is the column and row of the chunk
+	ChunkX int
+	ChunkY int
+	// TileX and TileY is the tile position of the top left corner of the chunk in the source map
+	TileX         int
+	TileY         int
+	WidthInTiles  int
+	HeightInTiles int
+	// Padding is the part of the chunk borrowed from neighbouring chunks
+	Padding Padding
+	// Empty is set for chunks without tiles or objects when using MarkEmptyChunks
+	Empty bool
+	// Hash of the chunk content when deduplicating, see HashChunk
+	Hash string
+	// Duplicate is set when deduplicating and an earlier chunk, DuplicateOf, has the same content
+	Duplicate   bool
+	DuplicateOf int
+}
+
+func (s *splitter) info(chunkIndex int, tm Tilemap) ChunkInfo {
+	return ChunkInfo{
+		Index:         chunkIndex,
+		ChunkX:        chunkIndex % s.widthInTilemaps,
+		ChunkY:        chunkIndex / s.widthInTilemaps,
+		TileX:         tm.OriginX,
+		TileY:         tm.OriginY,
+		WidthInTiles:  tm.WidthInTiles,
+		HeightInTiles: tm.HeightInTiles,
+		Padding:       tm.Padding,
+		Empty:         tm.Empty,
+		Hash:          tm.Hash,
+	}
+}
+
+// builtChunk is a chunk built by a worker of each
+type builtChunk struct {
+	index int
+	tm    Tilemap
+	err   error
+}
+
+// each builds the chunks with a pool of workers and hands them to fn in order, stopping at the first error.
+// Chunks finished ahead of the next one to hand over wait in a reorder buffer, at most workers chunks are
+// being built or waiting at a time.
+func (s *splitter) each(ctx context.Context, fn func(ChunkInfo, Tilemap) error) error {
+	ntilemaps := s.count()
+	workers := min(workerCount(s.opts.Workers), ntilemaps)
+	logrus.Debugf("creating %d tilemap(s) with %d layer(s) each using %d worker(s)", ntilemaps, countLayerType(s.tilemap.Layers, TileLayer), workers)
+
+	// the workers stop when a chunk fails, fn fails or the caller gives up
+	workCtx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	// a slot is taken for each chunk handed to the workers and freed when the chunk is handed to fn
+	slots := make(chan struct{}, workers)
+	indices := make(chan int)
+	results := make(chan builtChunk, workers)
+
+	go func() {
+		defer close(indices)
+		for chunkIndex := 0; chunkIndex < ntilemaps; chunkIndex++ {
+			select {
+			case slots <- struct{}{}:
+			case <-workCtx.Done():
+				return
+			}
+			select {
+			case indices <- chunkIndex:
+			case <-workCtx.Done():
+				return
 			}
+		}
+	}()
 
-			objects := []Object{}
-			for _, object := range layer.Objects {
-				tileX := int(object.X / float64(tm.TileWidth))
-				tileY := int(object.Y / float64(tm.TileHeight))
-				if tileX >= left && tileX < left+chunkWidth && tileY >= top && tileY < top+chunkHeight {
-					chunkX := math.Floor(float64(tileX) / float64(chunkWidth))
-					chunkY := math.Floor(float64(tileY) / float64(chunkHeight))
-					object.X -= chunkX * float64(chunkWidth*tm.TileWidth)
-					object.Y -= chunkY * float64(chunkHeight*tm.TileHeight)
-					objects = append(objects, object)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for chunkIndex := range indices {
+				tm, err := s.chunk(workCtx, chunkIndex)
+				select {
+				case results <- builtChunk{index: chunkIndex, tm: tm, err: err}:
+				case <-workCtx.Done():
+					return
 				}
 			}
-			tm.Layers[layerIndex].Objects = objects
+		}()
+	}
+
+	// index of the first chunk with each hash
+	firsts := map[string]int{}
+	pending := map[int]Tilemap{}
+
+	for next := 0; next < ntilemaps; {
+		tm, ok := pending[next]
+		if !ok {
+			select {
+			case r := <-results:
+				if r.err != nil {
+					return r.err
+				}
+				pending[r.index] = r.tm
+			case <-ctx.Done():
+				return checkContext(ctx, "split")
+			}
+			continue
 		}
+
+		delete(pending, next)
+		<-slots
+
+		if err := checkContext(ctx, "split"); err != nil {
+			return err
+		}
+
+		chunkIndex := next
+		next++
+
+		if tm.Empty && s.opts.EmptyChunks == SkipEmptyChunks {
+			logrus.Debugf("skipping empty tilemap %d", chunkIndex)
+			continue
+		}
+
+		info := s.info(chunkIndex, tm)
+		if info.Hash != "" && !info.Empty {
+			if first, ok := firsts[info.Hash]; ok {
+				info.Duplicate = true
+				info.DuplicateOf = first
+			} else {
+				firsts[info.Hash] = info.Index
+			}
+		}
+
+		if err := fn(info, tm); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SplitFunc splits the tilemap like Split, but hands each chunk to fn as soon as it's ready instead of
+// collecting them. Chunks are handed over in order and splitting stops at the first error.
+func SplitFunc(original Tilemap, opts SplitOptions, fn func(ChunkInfo, Tilemap) error) error {
+	return SplitFuncContext(context.Background(), original, opts, fn)
+}
+
+// SplitFuncContext is SplitFunc that stops between layers and chunks when the context is done
+func SplitFuncContext(ctx context.Context, original Tilemap, opts SplitOptions, fn func(ChunkInfo, Tilemap) error) error {
+	s, err := newSplitter(ctx, original, opts)
+	if err != nil {
+		return err
+	}
+
+	return s.each(ctx, fn)
+}
+
+// Split splits the tilemap into chunks of opts.ChunkWidth by opts.ChunkHeight tiles, numbered row by row
+func Split(original Tilemap, opts SplitOptions) ([]Tilemap, error) {
+	return SplitContext(context.Background(), original, opts)
+}
+
+// SplitContext is Split that stops between layers and chunks when the context is done
+func SplitContext(ctx context.Context, original Tilemap, opts SplitOptions) ([]Tilemap, error) {
+	var chunkedTilemaps []Tilemap
+	err := SplitFuncContext(ctx, original, opts, func(_ ChunkInfo, tm Tilemap) error {
 		chunkedTilemaps = append(chunkedTilemaps, tm)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return chunkedTilemaps, nil
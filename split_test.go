@@ -0,0 +1,101 @@
+package tmsplit
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSplitFuncOrder(t *testing.T) {
+	tm := parseFixture(t, "testdata/finite.tmx")
+
+	for _, workers := range []int{1, 3, 16} {
+		var indices []int
+		opts := SplitOptions{ChunkWidth: 1, ChunkHeight: 1, Workers: workers}
+		err := SplitFunc(tm, opts, func(info ChunkInfo, _ Tilemap) error {
+			indices = append(indices, info.Index)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("%d workers: failed to split: %v", workers, err)
+		}
+
+		if len(indices) != 12 {
+			t.Fatalf("%d workers: got %d chunks, want 12", workers, len(indices))
+		}
+		for i, index := range indices {
+			if index != i {
+				t.Errorf("%d workers: got chunk %d at position %d", workers, index, i)
+			}
+		}
+	}
+}
+
+func TestSplitFuncStops(t *testing.T) {
+	tm := parseFixture(t, "testdata/finite.tmx")
+	opts := SplitOptions{ChunkWidth: 1, ChunkHeight: 1, Workers: 4}
+
+	t.Run("error", func(t *testing.T) {
+		failed := errors.New("failed")
+		calls := 0
+		err := SplitFunc(tm, opts, func(info ChunkInfo, _ Tilemap) error {
+			calls++
+			if info.Index == 2 {
+				return failed
+			}
+			return nil
+		})
+		if !errors.Is(err, failed) {
+			t.Errorf("got error %v, want %v", err, failed)
+		}
+		if calls != 3 {
+			t.Errorf("got %d calls, want 3", calls)
+		}
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		calls := 0
+		err := SplitFuncContext(ctx, tm, opts, func(info ChunkInfo, _ Tilemap) error {
+			calls++
+			if info.Index == 2 {
+				cancel()
+			}
+			return nil
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("got error %v, want %v", err, context.Canceled)
+		}
+		if calls != 3 {
+			t.Errorf("got %d calls, want 3", calls)
+		}
+	})
+}
+
+func TestSplitShortLayerData(t *testing.T) {
+	short := Layer{ID: 2, Name: "short", Type: TileLayer, WidthInTiles: 4, HeightInTiles: 2, Opacity: 1, Visible: true,
+		Encoding: EncodingCSV, Data: "1,2,3"}
+
+	tests := []struct {
+		name   string
+		layers []Layer
+	}{
+		{"layer", []Layer{short}},
+		{"group", []Layer{{ID: 3, Name: "group", Type: Group, Opacity: 1, Visible: true, Layers: []Layer{short}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tm := objectsMap(Object{X: 4, Y: 4, Point: true})
+			tm.Layers = append(tm.Layers, tt.layers...)
+
+			_, err := Split(tm, SplitOptions{ChunkWidth: 2, ChunkHeight: 2, Workers: 2})
+			if err == nil || !strings.Contains(err.Error(), "layer 'short' has 3 tiles, expected 4x2") {
+				t.Errorf("got error %v, want short layer error", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package tmsplit
+
+import (
+	"errors"
+	"fmt"
+)
+
+// TileGrid is the decoded tile data of a tile layer, row by row
+type TileGrid struct {
+	// X and Y is the tile position of the top left tile, only infinite maps have a grid not starting at 0,0
+	X, Y          int
+	Width, Height int
+	GIDs          []GID
+}
+
+func (g TileGrid) contains(x, y int) bool {
+	return x >= g.X && x < g.X+g.Width && y >= g.Y && y < g.Y+g.Height
+}
+
+// At returns the gid at the tile position, zero if the position is outside the grid
+func (g TileGrid) At(x, y int) GID {
+	if !g.contains(x, y) {
+		return 0
+	}
+	return g.GIDs[(y-g.Y)*g.Width+x-g.X]
+}
+
+// Set sets the gid at the tile position
+func (g TileGrid) Set(x, y int, gid GID) error {
+	if !g.contains(x, y) {
+		return fmt.Errorf("tile %d,%d is outside the grid", x, y)
+	}
+	g.GIDs[(y-g.Y)*g.Width+x-g.X] = gid
+	return nil
+}
+
+// Tiles decodes the tile data of a tile layer. The chunks of infinite maps are combined into one grid covering all chunks.
+func (l Layer) Tiles() (TileGrid, error) {
+	if l.Type != TileLayer {
+		return TileGrid{}, fmt.Errorf("layer '%s' is not a tile layer", l.Name)
+	}
+
+	if len(l.Chunks) == 0 {
+		data, err := decodeLayerData(l)
+		if err != nil {
+			return TileGrid{}, fmt.Errorf("failed to decode layer '%s': %w", l.Name, err)
+		}
+
+		if len(data) != l.WidthInTiles*l.HeightInTiles {
+			return TileGrid{}, fmt.Errorf("layer '%s' has %d tiles, expected %dx%d", l.Name, len(data), l.WidthInTiles, l.HeightInTiles)
+		}
+
+		return TileGrid{Width: l.WidthInTiles, Height: l.HeightInTiles, GIDs: data}, nil
+	}
+
+	bounds := tileBounds{empty: true}
+	for _, c := range l.Chunks {
+		bounds.add(c)
+	}
+
+	grid := TileGrid{
+		X:      bounds.minX,
+		Y:      bounds.minY,
+		Width:  bounds.maxX - bounds.minX,
+		Height: bounds.maxY - bounds.minY,
+	}
+	grid.GIDs = make([]GID, grid.Width*grid.Height)
+
+	for _, c := range l.Chunks {
+		data, err := l.chunkTiles(c)
+		if err != nil {
+			return TileGrid{}, err
+		}
+
+		for row := 0; row < c.HeightInTiles; row++ {
+			begin := (c.Y-grid.Y+row)*grid.Width + c.X - grid.X
+			copy(grid.GIDs[begin:begin+c.WidthInTiles], data[row*c.WidthInTiles:(row+1)*c.WidthInTiles])
+		}
+	}
+
+	return grid, nil
+}
+
+func (l Layer) chunkTiles(c Chunk) ([]GID, error) {
+	data, err := decodeLayerData(Layer{Data: c.Data, Encoding: l.Encoding, Compression: l.Compression})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode chunk %d,%d of layer '%s': %w", c.X, c.Y, l.Name, err)
+	}
+
+	if len(data) != c.WidthInTiles*c.HeightInTiles {
+		return nil, fmt.Errorf("chunk %d,%d of layer '%s' has %d tiles, expected %dx%d", c.X, c.Y, l.Name, len(data), c.WidthInTiles, c.HeightInTiles)
+	}
+
+	return data, nil
+}
+
+// SetTiles encodes the grid into the tile data of the layer, keeping the encoding and compression of the layer.
+// The chunks of infinite maps are updated from the grid, tiles outside the existing chunks have to be empty.
+func (l *Layer) SetTiles(grid TileGrid) error {
+	if l.Type != TileLayer {
+		return fmt.Errorf("layer '%s' is not a tile layer", l.Name)
+	}
+
+	if len(grid.GIDs) != grid.Width*grid.Height {
+		return errors.New("grid size doesn't match its gids")
+	}
+
+	if len(l.Chunks) == 0 {
+		encoded, err := encodeLayerData(grid.GIDs, l.Encoding, l.Compression)
+		if err != nil {
+			return fmt.Errorf("failed to encode layer '%s': %w", l.Name, err)
+		}
+
+		l.Data = encoded
+		l.WidthInTiles = grid.Width
+		l.HeightInTiles = grid.Height
+		return nil
+	}
+
+	// the layer is left unchanged if a tile doesn't fit in its chunks
+	for y := grid.Y; y < grid.Y+grid.Height; y++ {
+		for x := grid.X; x < grid.X+grid.Width; x++ {
+			if grid.At(x, y) != 0 && !chunksContain(l.Chunks, x, y) {
+				return fmt.Errorf("tile %d,%d is outside the chunks of layer '%s'", x, y, l.Name)
+			}
+		}
+	}
+
+	for chunkIndex := range l.Chunks {
+		c := &l.Chunks[chunkIndex]
+
+		data := make([]GID, 0, c.WidthInTiles*c.HeightInTiles)
+		for y := c.Y; y < c.Y+c.HeightInTiles; y++ {
+			for x := c.X; x < c.X+c.WidthInTiles; x++ {
+				data = append(data, grid.At(x, y))
+			}
+		}
+
+		encoded, err := encodeLayerData(data, l.Encoding, l.Compression)
+		if err != nil {
+			return fmt.Errorf("failed to encode chunk %d,%d of layer '%s': %w", c.X, c.Y, l.Name, err)
+		}
+		c.Data = encoded
+	}
+
+	return nil
+}
+
+func chunksContain(chunks []Chunk, x, y int) bool {
+	for _, c := range chunks {
+		if x >= c.X && x < c.X+c.WidthInTiles && y >= c.Y && y < c.Y+c.HeightInTiles {
+			return true
+		}
+	}
+	return false
+}
+
+// TileAt returns the gid at the tile position, zero if there is no tile.
+// The layer is decoded on every call, use Tiles for many lookups.
+func (l Layer) TileAt(x, y int) (GID, error) {
+	grid, err := l.Tiles()
+	if err != nil {
+		return 0, err
+	}
+
+	return grid.At(x, y), nil
+}
+
+// SetTileAt sets the gid at the tile position and encodes the tile data of the layer again.
+// The layer is decoded on every call, use Tiles and SetTiles for many changes.
+func (l *Layer) SetTileAt(x, y int, gid GID) error {
+	grid, err := l.Tiles()
+	if err != nil {
+		return err
+	}
+
+	if err := grid.Set(x, y, gid); err != nil {
+		return fmt.Errorf("failed to set tile of layer '%s': %w", l.Name, err)
+	}
+
+	return l.SetTiles(grid)
+}
+
+// TilesetFor returns the tileset of the gid and the definition of the tile in it.
+// The tileset is nil if no tileset contains the gid and the tile is nil if the tileset has no definition for it.
+func (tm Tilemap) TilesetFor(gid GID) (*Tileset, *Tile) {
+	id := int(gid.TileID())
+	if id == 0 {
+		return nil, nil
+	}
+
+	var ts *Tileset
+	next := 0
+	for i := range tm.Tilesets {
+		firstgid := tm.Tilesets[i].FirstGID
+		if firstgid <= id && (ts == nil || firstgid > ts.FirstGID) {
+			ts = &tm.Tilesets[i]
+		} else if firstgid > id && (next == 0 || firstgid < next) {
+			next = firstgid
+		}
+	}
+
+	if ts == nil {
+		return nil, nil
+	}
+
+	// image collections can have tile ids beyond their tile count
+	localID := id - ts.FirstGID
+	if span := tilesetSpan(*ts, next); span > 0 && localID >= span {
+		return nil, nil
+	}
+
+	for i := range ts.Tiles {
+		if ts.Tiles[i].ID == localID {
+			return ts, &ts.Tiles[i]
+		}
+	}
+
+	return ts, nil
+}
@@ -0,0 +1,102 @@
+package tmsplit
+
+import (
+	"testing"
+)
+
+func TestTilesetFor(t *testing.T) {
+	sorted := sparseTilemap()
+	reversed := sparseTilemap()
+	for i, j := 0, len(reversed.Tilesets)-1; i < j; i, j = i+1, j-1 {
+		reversed.Tilesets[i], reversed.Tilesets[j] = reversed.Tilesets[j], reversed.Tilesets[i]
+	}
+
+	tests := []struct {
+		gid     GID
+		tileset string
+		tile    int // -1 for no tile definition
+	}{
+		{0, "", -1},
+		{1, "icons", 0},
+		{2, "icons", -1},
+		{6, "icons", 5},
+		{NewGID(6, true, true, false), "icons", 5},
+		{7, "ts", -1},
+		{22, "ts", -1},
+		{23, "unused", -1},
+		{26, "unused", -1},
+		{27, "", -1},
+	}
+
+	for _, tm := range []Tilemap{sorted, reversed} {
+		for _, tt := range tests {
+			ts, tile := tm.TilesetFor(tt.gid)
+
+			name := ""
+			if ts != nil {
+				name = ts.Name
+			}
+			if name != tt.tileset {
+				t.Errorf("gid %d: got tileset '%s', want '%s'", tt.gid, name, tt.tileset)
+			}
+
+			id := -1
+			if tile != nil {
+				id = tile.ID
+			}
+			if id != tt.tile {
+				t.Errorf("gid %d: got tile %d, want %d", tt.gid, id, tt.tile)
+			}
+		}
+	}
+}
+
+func TestTilesetForPrunedChunk(t *testing.T) {
+	chunks, err := Split(sparseTilemap(), SplitOptions{ChunkWidth: 1, ChunkHeight: 1, PruneTilesets: true})
+	if err != nil {
+		t.Fatalf("failed to split: %v", err)
+	}
+
+	// the chunk keeps only the image collection, with its sparse tile as the last gid
+	ts, tile := chunks[0].TilesetFor(6)
+	if ts == nil || ts.Name != "icons" {
+		t.Fatalf("got tileset %v, want icons", ts)
+	}
+	if tile == nil || tile.Image != "f.png" {
+		t.Errorf("got tile %v, want f.png", tile)
+	}
+}
+
+func TestSetTileAtChunkGap(t *testing.T) {
+	layer := Layer{
+		Name: "ground", Type: TileLayer, Encoding: EncodingCSV,
+		Chunks: []Chunk{
+			{X: 0, Y: 0, WidthInTiles: 2, HeightInTiles: 2, Data: "1,1,1,1"},
+			{X: 4, Y: 4, WidthInTiles: 2, HeightInTiles: 2, Data: "2,2,2,2"},
+		},
+	}
+
+	if err := layer.SetTileAt(5, 5, 3); err != nil {
+		t.Fatalf("failed to set tile in a chunk: %v", err)
+	}
+	if err := layer.SetTileAt(2, 2, 0); err != nil {
+		t.Errorf("failed to clear tile between the chunks: %v", err)
+	}
+	// tile 2,2 is inside the bounds of the chunks but in none of them
+	if err := layer.SetTileAt(2, 2, 9); err == nil {
+		t.Errorf("set tile between the chunks")
+	}
+
+	for _, tt := range []struct {
+		x, y int
+		gid  GID
+	}{{0, 0, 1}, {2, 2, 0}, {5, 5, 3}} {
+		gid, err := layer.TileAt(tt.x, tt.y)
+		if err != nil {
+			t.Fatalf("failed to get tile: %v", err)
+		}
+		if gid != tt.gid {
+			t.Errorf("tile %d,%d: got gid %d, want %d", tt.x, tt.y, gid, tt.gid)
+		}
+	}
+}
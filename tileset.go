@@ -0,0 +1,87 @@
+package tmsplit
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// ParseTilesetJSON parses an external Tiled JSON tileset
+func ParseTilesetJSON(b []byte) (Tileset, error) {
+	ts := Tileset{}
+	if err := json.Unmarshal(b, &ts); err != nil {
+		return Tileset{}, fmt.Errorf("failed to json decode tileset: %w", err)
+	}
+
+	return ts, nil
+}
+
+// ParseTilesetXML parses an external Tiled TSX (xml) tileset
+func ParseTilesetXML(b []byte) (Tileset, error) {
+	ts := Tileset{}
+	if err := xml.Unmarshal(b, &ts); err != nil {
+		return Tileset{}, fmt.Errorf("failed to xml decode tileset: %w", err)
+	}
+
+	fixTileset(&ts)
+
+	return ts, nil
+}
+
+func loadTileset(fsys fs.FS, name string) (Tileset, error) {
+	b, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return Tileset{}, err
+	}
+
+	switch strings.ToLower(path.Ext(name)) {
+	case ".json", ".tsj":
+		return ParseTilesetJSON(b)
+	default:
+		return ParseTilesetXML(b)
+	}
+}
+
+// ResolveTilesets loads the external tilesets referenced by Tileset.Source from fsys, relative to dir
+// which should be the directory of the map file. Image paths of resolved tilesets are made relative to dir.
+//
+// If embed is true the resolved tilesets replace the references, otherwise the reference is kept and
+// only the source and first gid are written when the tilemap is encoded.
+func ResolveTilesets(tilemap Tilemap, fsys fs.FS, dir string, embed bool) (Tilemap, error) {
+	tilesets := make([]Tileset, len(tilemap.Tilesets))
+	for tsindex, ts := range tilemap.Tilesets {
+		if ts.Source == "" {
+			tilesets[tsindex] = ts
+			continue
+		}
+
+		name := path.Join(dir, ts.Source)
+		resolved, err := loadTileset(fsys, name)
+		if err != nil {
+			return Tilemap{}, fmt.Errorf("failed to load tileset '%s': %w", ts.Source, err)
+		}
+
+		if resolved.Image != "" {
+			resolved.Image = path.Join(path.Dir(ts.Source), resolved.Image)
+		}
+		for tileIndex := range resolved.Tiles {
+			if t := &resolved.Tiles[tileIndex]; t.Image != "" {
+				t.Image = path.Join(path.Dir(ts.Source), t.Image)
+			}
+		}
+
+		resolved.FirstGID = ts.FirstGID
+		resolved.Source = ts.Source
+		if embed {
+			resolved.Source = ""
+		}
+
+		tilesets[tsindex] = resolved
+	}
+
+	tilemap.Tilesets = tilesets
+	return tilemap, nil
+}
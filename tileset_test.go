@@ -0,0 +1,45 @@
+package tmsplit
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestResolveImageCollection(t *testing.T) {
+	fsys := fstest.MapFS{
+		"maps/tilesets/icons.tsx": {Data: []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<tileset version="1.5" name="icons" tilewidth="32" tileheight="32" tilecount="2" columns="0">
+ <grid orientation="orthogonal" width="1" height="1"/>
+ <tile id="0">
+  <image width="32" height="32" source="images/sword.png"/>
+ </tile>
+ <tile id="3">
+  <image width="16" height="24" source="../shared/shield.png"/>
+ </tile>
+</tileset>
+`)},
+	}
+
+	tilemap := Tilemap{Tilesets: []Tileset{{FirstGID: 1, Source: "tilesets/icons.tsx"}}}
+	for _, embed := range []bool{false, true} {
+		resolved, err := ResolveTilesets(tilemap, fsys, "maps", embed)
+		if err != nil {
+			t.Fatalf("failed to resolve tilesets: %v", err)
+		}
+
+		tiles := resolved.Tilesets[0].Tiles
+		want := []Tile{
+			{ID: 0, Image: "tilesets/images/sword.png", ImageWidth: 32, ImageHeight: 32},
+			{ID: 3, Image: "shared/shield.png", ImageWidth: 16, ImageHeight: 24},
+		}
+		if len(tiles) != len(want) {
+			t.Fatalf("got %d tiles, want %d", len(tiles), len(want))
+		}
+		for i, tile := range tiles {
+			if tile.ID != want[i].ID || tile.Image != want[i].Image || tile.ImageWidth != want[i].ImageWidth || tile.ImageHeight != want[i].ImageHeight {
+				t.Errorf("embed %t: got tile %d with image '%s' %dx%d, want tile %d with '%s' %dx%d", embed,
+					tile.ID, tile.Image, tile.ImageWidth, tile.ImageHeight, want[i].ID, want[i].Image, want[i].ImageWidth, want[i].ImageHeight)
+			}
+		}
+	}
+}